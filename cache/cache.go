@@ -0,0 +1,470 @@
+package cache
+
+import (
+	"container/list"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rickb777/acceptable/data"
+	"github.com/rickb777/acceptable/header"
+	"github.com/rickb777/acceptable/headername"
+)
+
+// TagHeader is the response header used by handlers to tag cached responses. Its value is a
+// comma-separated list of tags. It is removed before responses are sent.
+const TagHeader = "Cache-Tag"
+
+// DefaultMaxEntries is used when Config.MaxEntries is zero.
+const DefaultMaxEntries = 1000
+
+// Config holds the settings for a Cache.
+type Config struct {
+	// MaxEntries is the maximum number of stored responses (DefaultMaxEntries if zero).
+	MaxEntries int
+	// MaxBytes is the maximum total size of the stored response bodies (unlimited if zero).
+	MaxBytes int64
+	// Key gets the primary cache key of a request; the default is its URL path and query.
+	Key func(req *http.Request) string
+	// Now provides the current time; the default is time.Now.
+	Now func() time.Time
+}
+
+// Cache stores rendered responses. It is safe for concurrent use.
+type Cache struct {
+	config Config
+
+	mu    sync.Mutex
+	lru   *list.List // of *entry, most recently used first
+	byKey map[string][]*list.Element
+	bytes int64
+}
+
+// entry is one stored response, i.e. one variant of a URL.
+type entry struct {
+	key        string
+	vary       []string // the request headers listed in the Vary response header
+	varyValues []string // the corresponding request header values
+	header     http.Header
+	body       []byte
+	tags       []string
+	storedAt   time.Time
+	initialAge time.Duration
+	lifetime   time.Duration
+}
+
+// New creates a cache.
+func New(config Config) *Cache {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultMaxEntries
+	}
+	if config.Key == nil {
+		config.Key = func(req *http.Request) string { return req.URL.RequestURI() }
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Cache{
+		config: config,
+		lru:    list.New(),
+		byKey:  make(map[string][]*list.Element),
+	}
+}
+
+// Len gets the number of stored responses.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Invalidate removes all the stored variants for a key (see Config.Key).
+func (c *Cache) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, el := range slices.Clone(c.byKey[key]) {
+		c.remove(el)
+	}
+}
+
+// InvalidateTag removes all the stored responses that have a tag (see TagHeader).
+func (c *Cache) InvalidateTag(tag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if slices.Contains(el.Value.(*entry).tags, tag) {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+// Handler wraps a handler so that its responses are cached.
+func (c *Cache) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		c.serveHTTP(next, rw, req)
+	})
+}
+
+func (c *Cache) serveHTTP(next http.Handler, rw http.ResponseWriter, req *http.Request) {
+	key := c.config.Key(req)
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		rec := newRecorder(rw, false)
+		next.ServeHTTP(rec, req)
+		rec.finish()
+		if rec.status < 400 {
+			c.Invalidate(key) // RFC-9111 section 4.4
+		}
+		return
+	}
+
+	reqCC := header.RequestCacheControl(req)
+	if reqCC.NoStore || req.Header.Get(headername.Range) != "" {
+		next.ServeHTTP(newRecorder(rw, false), req)
+		return
+	}
+
+	now := c.config.Now()
+	e, fresh, validators := c.lookup(key, req, now)
+
+	switch {
+	case e != nil && fresh && !reqCC.ForcesRevalidation():
+		c.serveStored(rw, req, e, now)
+	case e != nil && validators != nil:
+		c.revalidate(next, rw, req, key, e, validators)
+	default:
+		c.fetch(next, rw, req, key)
+	}
+}
+
+// fetch passes the request to the handler, storing the response if possible.
+func (c *Cache) fetch(next http.Handler, rw http.ResponseWriter, req *http.Request, key string) {
+	rec := newRecorder(rw, req.Method == http.MethodGet)
+	next.ServeHTTP(rec, req)
+	rec.finish()
+
+	if rec.passedThrough {
+		return
+	}
+
+	now := c.config.Now()
+	if e := c.newEntry(key, req, rec, now); e != nil {
+		c.store(e)
+	}
+	rec.writeTo(rw, req)
+}
+
+// revalidate sends a conditional request to the handler using the validators of a stored
+// response. A 304-Not Modified response refreshes the stored response, which is then used.
+func (c *Cache) revalidate(next http.Handler, rw http.ResponseWriter, req *http.Request, key string, e *entry, validators http.Header) {
+	conditional := req.Clone(req.Context())
+	conditional.Method = http.MethodGet
+	// the client's own conditions and cache directives are for the cache, not the handler
+	for _, h := range []string{headername.IfMatch, headername.IfUnmodifiedSince, headername.IfNoneMatch, headername.IfModifiedSince, headername.IfRange, headername.CacheControl, headername.Pragma} {
+		conditional.Header.Del(h)
+	}
+	if etag := validators.Get(headername.ETag); etag != "" {
+		conditional.Header.Set(headername.IfNoneMatch, etag)
+	} else {
+		conditional.Header.Set(headername.IfModifiedSince, validators.Get(headername.LastModified))
+	}
+
+	rec := newRecorder(rw, true)
+	next.ServeHTTP(rec, conditional)
+	rec.finish()
+
+	if rec.passedThrough {
+		return
+	}
+
+	now := c.config.Now()
+
+	if rec.status == http.StatusNotModified {
+		c.mu.Lock()
+		e.refresh(rec.header, now)
+		c.mu.Unlock()
+		c.serveStored(rw, req, e, now)
+		return
+	}
+
+	c.Invalidate(key)
+
+	if rec.status == http.StatusOK {
+		if fresh := c.newEntry(key, req, rec, now); fresh != nil {
+			c.store(fresh)
+			c.serveStored(rw, req, fresh, now)
+			return
+		}
+	}
+
+	// the client's own conditions were removed for revalidation, so are applied here
+	if rec.status == http.StatusOK && c.answerConditional(rw, req, rec.header) {
+		return
+	}
+	rec.writeTo(rw, req)
+}
+
+// serveStored answers a request using a stored response, including conditional requests.
+func (c *Cache) serveStored(rw http.ResponseWriter, req *http.Request, e *entry, now time.Time) {
+	c.mu.Lock()
+	hdr := e.header.Clone()
+	body := e.body
+	age := e.age(now)
+	c.mu.Unlock()
+
+	hdr.Set(headername.Age, strconv.FormatInt(int64(age/time.Second), 10))
+
+	if c.answerConditional(rw, req, hdr) {
+		return
+	}
+
+	for h, v := range hdr {
+		rw.Header()[h] = v
+	}
+	rw.Header().Set(headername.ContentLength, strconv.Itoa(len(body)))
+	rw.WriteHeader(http.StatusOK)
+	if req.Method != http.MethodHead {
+		_, _ = rw.Write(body)
+	}
+}
+
+// answerConditional evaluates the request preconditions using the validators in hdr and
+// responds with 304-Not Modified or 412-Precondition Failed if needed.
+func (c *Cache) answerConditional(rw http.ResponseWriter, req *http.Request, hdr http.Header) bool {
+	status := data.EvaluatePreconditions(req, metadataOf(hdr))
+	switch status {
+	case http.StatusNotModified:
+		for _, h := range []string{headername.CacheControl, headername.ContentLocation, headername.Date, headername.ETag, headername.Expires, headername.LastModified, headername.Vary} {
+			for _, v := range hdr.Values(h) {
+				rw.Header().Add(h, v)
+			}
+		}
+		rw.WriteHeader(status)
+		return true
+	case http.StatusPreconditionFailed:
+		rw.WriteHeader(status)
+		return true
+	}
+	return false
+}
+
+func metadataOf(hdr http.Header) *data.Metadata {
+	meta := &data.Metadata{}
+	if etags := header.ETagsOf(hdr.Get(headername.ETag)); len(etags) == 1 {
+		meta.Hash = etags[0].Hash
+		meta.Weak = etags[0].Weak
+	}
+	meta.LastModified, _ = header.ParseHTTPDateTime(hdr.Get(headername.LastModified))
+	return meta
+}
+
+//-------------------------------------------------------------------------------------------------
+
+// newEntry creates an entry from a response, provided that it can be stored (RFC-9111 section 3).
+func (c *Cache) newEntry(key string, req *http.Request, rec *recorder, now time.Time) *entry {
+	if rec.status != http.StatusOK || req.Method != http.MethodGet {
+		return nil
+	}
+
+	hdr := rec.header
+	if hdr.Get(headername.SetCookie) != "" {
+		return nil
+	}
+
+	cc := header.ParseCacheControl(strings.Join(hdr.Values(headername.CacheControl), ","))
+	if cc.NoStore || cc.Private {
+		return nil
+	}
+
+	if req.Header.Get(headername.Authorization) != "" && !cc.Public && !cc.MustRevalidate && cc.SMaxAge == nil {
+		return nil
+	}
+
+	vary := varyNames(hdr)
+	if slices.Contains(vary, "*") {
+		return nil
+	}
+
+	e := &entry{
+		key:        key,
+		vary:       vary,
+		varyValues: varyValues(req, vary),
+		header:     hdr.Clone(),
+		body:       rec.body.Bytes(),
+		tags:       rec.tags,
+	}
+	e.refresh(hdr, now)
+
+	if e.lifetime <= 0 && !e.hasValidator() {
+		return nil // it would never be used
+	}
+
+	if c.config.MaxBytes > 0 && int64(len(e.body)) > c.config.MaxBytes {
+		return nil
+	}
+
+	return e
+}
+
+// refresh updates the freshness of an entry using the headers of a response (RFC-9111
+// section 4.3.4).
+func (e *entry) refresh(hdr http.Header, now time.Time) {
+	for _, h := range []string{headername.CacheControl, headername.ETag, headername.Expires, headername.LastModified} {
+		if v := hdr.Values(h); len(v) > 0 {
+			e.header[http.CanonicalHeaderKey(h)] = v
+		}
+	}
+
+	e.storedAt = now
+	e.initialAge = 0
+	if age, err := strconv.ParseInt(hdr.Get(headername.Age), 10, 64); err == nil && age > 0 {
+		e.initialAge = time.Duration(age) * time.Second
+	}
+
+	e.lifetime = freshnessLifetime(e.header, now)
+}
+
+// freshnessLifetime determines how long a response is fresh (RFC-9111 section 4.2.1).
+func freshnessLifetime(hdr http.Header, now time.Time) time.Duration {
+	cc := header.ParseCacheControl(strings.Join(hdr.Values(headername.CacheControl), ","))
+	switch {
+	case cc.NoCache:
+		return 0
+	case cc.SMaxAge != nil:
+		return *cc.SMaxAge
+	case cc.MaxAge != nil:
+		return *cc.MaxAge
+	}
+
+	if expires := hdr.Get(headername.Expires); expires != "" {
+		at, err := header.ParseHTTPDateTime(expires)
+		if err != nil {
+			return 0 // invalid dates mean the response has already expired
+		}
+		date, err := header.ParseHTTPDateTime(hdr.Get(headername.Date))
+		if err != nil {
+			date = now
+		}
+		return at.Sub(date)
+	}
+
+	return 0
+}
+
+func (e *entry) age(now time.Time) time.Duration {
+	return e.initialAge + now.Sub(e.storedAt)
+}
+
+func (e *entry) hasValidator() bool {
+	return e.header.Get(headername.ETag) != "" || e.header.Get(headername.LastModified) != ""
+}
+
+func (e *entry) size() int64 {
+	return int64(len(e.body))
+}
+
+// negotiated lists the request headers used for content negotiation. The negotiation only
+// lists those that were present in the Vary header, so a response to a request without
+// them would otherwise be a match for any request.
+var negotiated = []string{headername.Accept, headername.AcceptLanguage, headername.AcceptCharset, headername.AcceptEncoding}
+
+// varyNames gets the request headers that select a variant, i.e. those in the Vary header
+// along with the negotiated headers.
+func varyNames(hdr http.Header) []string {
+	names := slices.Clone(negotiated)
+	for _, v := range hdr.Values(headername.Vary) {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name != "" && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func varyValues(req *http.Request, names []string) []string {
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = normalise(req.Header.Values(name))
+	}
+	return values
+}
+
+// normalise combines header field values so that insignificant differences in whitespace
+// and case do not give different variants (RFC-9111 section 4.1).
+func normalise(values []string) string {
+	var parts []string
+	for _, v := range values {
+		for _, p := range strings.Split(v, ",") {
+			parts = append(parts, strings.ToLower(strings.ReplaceAll(p, " ", "")))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+//-------------------------------------------------------------------------------------------------
+
+// lookup finds the stored variant that matches a request, if any. It also determines whether
+// it is fresh and gets its validators, which are nil if it has none.
+func (c *Cache) lookup(key string, req *http.Request, now time.Time) (*entry, bool, http.Header) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, el := range c.byKey[key] {
+		e := el.Value.(*entry)
+		if slices.Equal(e.varyValues, varyValues(req, e.vary)) {
+			c.lru.MoveToFront(el)
+
+			fresh := e.age(now) < e.lifetime
+
+			var validators http.Header
+			if e.hasValidator() {
+				validators = http.Header{}
+				validators.Set(headername.ETag, e.header.Get(headername.ETag))
+				validators.Set(headername.LastModified, e.header.Get(headername.LastModified))
+			}
+
+			return e, fresh, validators
+		}
+	}
+	return nil, false, nil
+}
+
+func (c *Cache) store(e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// replace the same variant, if present
+	for _, el := range slices.Clone(c.byKey[e.key]) {
+		old := el.Value.(*entry)
+		if slices.Equal(old.vary, e.vary) && slices.Equal(old.varyValues, e.varyValues) {
+			c.remove(el)
+		}
+	}
+
+	el := c.lru.PushFront(e)
+	c.byKey[e.key] = append(c.byKey[e.key], el)
+	c.bytes += e.size()
+
+	for c.lru.Len() > c.config.MaxEntries || (c.config.MaxBytes > 0 && c.bytes > c.config.MaxBytes) {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry)
+	c.bytes -= e.size()
+
+	variants := slices.DeleteFunc(c.byKey[e.key], func(other *list.Element) bool { return other == el })
+	if len(variants) == 0 {
+		delete(c.byKey, e.key)
+	} else {
+		c.byKey[e.key] = variants
+	}
+}
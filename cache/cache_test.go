@@ -0,0 +1,275 @@
+package cache_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rickb777/acceptable"
+	"github.com/rickb777/acceptable/cache"
+	"github.com/rickb777/acceptable/data"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+type product struct {
+	Name string
+}
+
+type fixture struct {
+	cache   *cache.Cache
+	handler http.Handler
+	now     time.Time
+	calls   int           // number of times the content was obtained
+	last    *http.Request // the latest request seen by the handler
+}
+
+// newFixture creates a handler that renders JSON or XML, with the given metadata.
+func newFixture(config cache.Config, decorate func(*data.Value) *data.Value) *fixture {
+	f := &fixture{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	config.Now = func() time.Time { return f.now }
+	f.cache = cache.New(config)
+
+	f.handler = f.cache.Handler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		f.last = req
+		if req.Method == http.MethodPut {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		d := decorate(data.Lazy(func(data.Chosen) (any, error) {
+			f.calls++
+			return product{Name: "widget"}, nil
+		}))
+
+		_ = acceptable.RenderBestMatch(rw, req,
+			offer.JSON().With(d, "*"),
+			offer.XML("product").With(d, "*"))
+	}))
+
+	return f
+}
+
+func (f *fixture) do(method, url string, hdrs ...string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, nil)
+	for i := 1; i < len(hdrs); i += 2 {
+		req.Header.Set(hdrs[i-1], hdrs[i])
+	}
+	w := httptest.NewRecorder()
+	f.handler.ServeHTTP(w, req)
+	return w
+}
+
+func Test_fresh_response_should_be_served_from_cache(t *testing.T) {
+	// Given ...
+	f := newFixture(cache.Config{}, func(v *data.Value) *data.Value { return v.MaxAge(time.Minute) })
+	first := f.do("GET", "/p/1", Accept, "application/json")
+	f.now = f.now.Add(10 * time.Second)
+
+	// When ...
+	w := f.do("GET", "/p/1", Accept, "application/json")
+
+	// Then ...
+	expect.Number(f.calls).ToBe(t, 1)
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Body.String()).ToBe(t, first.Body.String())
+	expect.String(w.Header().Get(ContentType)).ToBe(t, "application/json")
+	expect.String(w.Header().Get(Age)).ToBe(t, "10")
+	expect.String(w.Header().Get(CacheControl)).ToBe(t, "max-age=60")
+}
+
+func Test_variants_should_be_stored_separately(t *testing.T) {
+	// Given ...
+	f := newFixture(cache.Config{}, func(v *data.Value) *data.Value { return v.MaxAge(time.Minute) })
+
+	// When ...
+	asJSON := f.do("GET", "/p/1", Accept, "application/json")
+	asXML := f.do("GET", "/p/1", Accept, "application/xml")
+	again := f.do("GET", "/p/1", Accept, "application/json")
+
+	// Then ...
+	expect.Number(f.calls).ToBe(t, 2)
+	expect.Number(f.cache.Len()).ToBe(t, 2)
+	expect.String(asXML.Header().Get(ContentType)).ToBe(t, "application/xml")
+	expect.String(again.Header().Get(ContentType)).ToBe(t, "application/json")
+	expect.String(again.Body.String()).ToBe(t, asJSON.Body.String())
+}
+
+func Test_stale_response_should_be_revalidated_using_etag(t *testing.T) {
+	// Given ...
+	f := newFixture(cache.Config{}, func(v *data.Value) *data.Value { return v.ETag("v1") })
+	first := f.do("GET", "/p/1")
+
+	// When ...
+	w := f.do("GET", "/p/1")
+
+	// Then ...
+	expect.Number(f.calls).ToBe(t, 1) // the handler gave 304 without obtaining the content
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Header().Get(ETag)).ToBe(t, `"v1"`)
+	expect.String(w.Body.String()).ToBe(t, first.Body.String())
+}
+
+func Test_expired_response_should_be_revalidated(t *testing.T) {
+	// Given ...
+	f := newFixture(cache.Config{}, func(v *data.Value) *data.Value { return v.ETag("v1").MaxAge(time.Minute) })
+	f.do("GET", "/p/1")
+	f.do("GET", "/p/1")
+	f.now = f.now.Add(61 * time.Second)
+
+	// When ...
+	w := f.do("GET", "/p/1")
+
+	// Then ...
+	expect.Number(f.calls).ToBe(t, 1)
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Header().Get(Age)).ToBe(t, "0")
+}
+
+func Test_client_conditional_request_should_be_answered_from_cache(t *testing.T) {
+	// Given ...
+	f := newFixture(cache.Config{}, func(v *data.Value) *data.Value { return v.ETag("v1").MaxAge(time.Minute) })
+	f.do("GET", "/p/1")
+
+	// When ...
+	w := f.do("GET", "/p/1", IfNoneMatch, `"v1"`)
+
+	// Then ...
+	expect.Number(f.calls).ToBe(t, 1)
+	expect.Number(w.Code).ToBe(t, 304)
+	expect.String(w.Header().Get(ETag)).ToBe(t, `"v1"`)
+	expect.String(w.Body.String()).ToBe(t, "")
+}
+
+func Test_client_forcing_revalidation_should_bypass_fresh_response(t *testing.T) {
+	// Given ...
+	f := newFixture(cache.Config{}, func(v *data.Value) *data.Value { return v.MaxAge(time.Minute) })
+	f.do("GET", "/p/1")
+
+	// When ...
+	w := f.do("GET", "/p/1", CacheControl, "no-cache")
+
+	// Then ...
+	expect.Number(f.calls).ToBe(t, 2) // there is no validator
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.Number(f.cache.Len()).ToBe(t, 1)
+}
+
+func Test_client_forcing_revalidation_should_revalidate_using_last_modified(t *testing.T) {
+	// Given ...
+	modified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := newFixture(cache.Config{}, func(v *data.Value) *data.Value { return v.LastModified(modified).MaxAge(time.Minute) })
+	first := f.do("GET", "/p/1")
+
+	// When ...
+	w := f.do("GET", "/p/1", CacheControl, "max-age=0")
+
+	// Then ...
+	expect.Number(f.calls).ToBe(t, 1) // the handler gave 304 without obtaining the content
+	expect.String(f.last.Header.Get(IfModifiedSince)).ToBe(t, "Wed, 01 Jan 2020 00:00:00 GMT")
+	expect.String(f.last.Header.Get(CacheControl)).ToBe(t, "")
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Body.String()).ToBe(t, first.Body.String())
+}
+
+func Test_invalidation_by_key_and_tag(t *testing.T) {
+	// Given ...
+	f := newFixture(cache.Config{}, func(v *data.Value) *data.Value {
+		return v.MaxAge(time.Minute).With(cache.TagHeader, "products, widgets")
+	})
+	w := f.do("GET", "/p/1")
+	f.do("GET", "/p/2")
+	f.do("GET", "/p/2", Accept, "application/xml")
+	expect.Number(f.cache.Len()).ToBe(t, 3)
+	expect.String(w.Header().Get(cache.TagHeader)).ToBe(t, "")
+
+	// When ...
+	f.cache.Invalidate("/p/2")
+
+	// Then ...
+	expect.Number(f.cache.Len()).ToBe(t, 1)
+
+	// When ...
+	f.cache.InvalidateTag("widgets")
+
+	// Then ...
+	expect.Number(f.cache.Len()).ToBe(t, 0)
+}
+
+func Test_unsafe_request_should_invalidate(t *testing.T) {
+	// Given ...
+	f := newFixture(cache.Config{}, func(v *data.Value) *data.Value { return v.MaxAge(time.Minute) })
+	f.do("GET", "/p/1")
+
+	// When ...
+	w := f.do("PUT", "/p/1")
+
+	// Then ...
+	expect.Number(w.Code).ToBe(t, 204)
+	expect.Number(f.cache.Len()).ToBe(t, 0)
+}
+
+func Test_cache_should_be_bounded(t *testing.T) {
+	// Given ...
+	f := newFixture(cache.Config{MaxEntries: 2}, func(v *data.Value) *data.Value { return v.MaxAge(time.Minute) })
+	f.do("GET", "/p/1")
+	f.do("GET", "/p/2")
+	f.do("GET", "/p/1") // now more recently used than /p/2
+
+	// When ...
+	f.do("GET", "/p/3")
+
+	// Then ...
+	expect.Number(f.cache.Len()).ToBe(t, 2)
+	expect.Number(f.calls).ToBe(t, 3)
+	f.do("GET", "/p/1")
+	expect.Number(f.calls).ToBe(t, 3)
+	f.do("GET", "/p/2")
+	expect.Number(f.calls).ToBe(t, 4)
+}
+
+func Test_uncacheable_responses_should_not_be_stored(t *testing.T) {
+	cases := []func(*data.Value) *data.Value{
+		func(v *data.Value) *data.Value { return v },                                                   // no freshness or validator
+		func(v *data.Value) *data.Value { return v.MaxAge(time.Minute).With(CacheControl, "private") }, // private
+		func(v *data.Value) *data.Value { return v.ETag("v1").With(CacheControl, "no-store") },         // no-store
+		func(v *data.Value) *data.Value { return v.MaxAge(time.Minute).With(SetCookie, "a=b") },        // cookie
+	}
+
+	for i, decorate := range cases {
+		// Given ...
+		f := newFixture(cache.Config{}, decorate)
+
+		// When ...
+		f.do("GET", "/p/1")
+		f.do("GET", "/p/1")
+
+		// Then ...
+		expect.Number(f.cache.Len()).I(i).ToBe(t, 0)
+		expect.Number(f.calls).I(i).ToBe(t, 2)
+	}
+}
+
+func Test_streamed_response_should_pass_through(t *testing.T) {
+	// Given ...
+	c := cache.New(cache.Config{})
+	h := c.Handler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set(CacheControl, "max-age=60")
+		rw.Write([]byte("one"))
+		http.NewResponseController(rw).Flush()
+		rw.Write([]byte("two"))
+	}))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	// When ...
+	h.ServeHTTP(w, req)
+
+	// Then ...
+	expect.Bool(w.Flushed).ToBeTrue(t)
+	expect.String(w.Body.String()).ToBe(t, "onetwo")
+	expect.Number(c.Len()).ToBe(t, 0)
+}
@@ -0,0 +1,26 @@
+// Package cache provides an in-process cache for rendered responses. It is used as HTTP middleware
+// in front of handlers that use content negotiation (e.g. via acceptable.RenderBestMatch).
+//
+// Each response is stored as a variant of its URL, keyed by the values of the Accept, Accept-Language,
+// Accept-Charset and Accept-Encoding request headers, plus any others listed in its Vary header. So the
+// JSON, XML and HTML representations of a resource, as well as their languages, character sets and
+// content codings, are stored separately.
+//
+// Freshness is determined by the Cache-Control and Expires headers of each response (RFC-9111). Stale
+// responses, and those stored with "no-cache", are revalidated by sending a conditional request to the
+// handler using the stored ETag and Last-Modified; these come from the handler's data.Metadata, so a
+// 304-Not Modified from the handler avoids rendering the response again. Conditional requests from
+// clients are answered from the cache, including 304-Not Modified.
+//
+// The cache is bounded by the number of entries and, optionally, by their total size; the least recently
+// used entries are evicted first. Entries can be invalidated by key (all variants of a URL) or by tag.
+// Handlers attach tags to responses using the TagHeader response header, e.g.
+//
+//	d := data.Of(product).With(cache.TagHeader, "products, product-42")
+//
+// This header is not sent to clients.
+//
+// Requests using unsafe methods (e.g. PUT, POST, DELETE) are passed to the handler and, if they succeed,
+// invalidate the URL. Responses that are private, that have Set-Cookie, or that are streamed using
+// http.Flusher are not stored.
+package cache
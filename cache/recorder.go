@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// recorder captures a response from a handler so that it can be stored. If the handler
+// flushes the response, capturing stops and the response passes through instead, because
+// streamed responses are not stored.
+type recorder struct {
+	rw            http.ResponseWriter
+	header        http.Header
+	status        int
+	wroteHeader   bool
+	body          bytes.Buffer
+	tags          []string
+	passedThrough bool
+}
+
+// newRecorder creates a recorder. If capture is false, the response passes through
+// immediately; only the status and tags are recorded.
+func newRecorder(rw http.ResponseWriter, capture bool) *recorder {
+	if !capture {
+		return &recorder{rw: rw, header: rw.Header(), passedThrough: true}
+	}
+	return &recorder{rw: rw, header: make(http.Header)}
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(statusCode int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = statusCode
+
+	for _, v := range r.header.Values(TagHeader) {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				r.tags = append(r.tags, tag)
+			}
+		}
+	}
+	r.header.Del(TagHeader)
+
+	if r.passedThrough {
+		r.rw.WriteHeader(statusCode)
+	}
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.passedThrough {
+		return r.rw.Write(b)
+	}
+	return r.body.Write(b)
+}
+
+// Flush sends the response captured so far, after which the response passes through.
+func (r *recorder) Flush() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.passedThrough {
+		r.passedThrough = true
+		for h, v := range r.header {
+			r.rw.Header()[h] = v
+		}
+		r.rw.WriteHeader(r.status)
+		_, _ = r.rw.Write(r.body.Bytes())
+		r.body.Reset()
+	}
+	_ = http.NewResponseController(r.rw).Flush()
+}
+
+// Unwrap returns the underlying response writer; this is used by http.ResponseController.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.rw
+}
+
+// finish completes the response in the same way as net/http does for handlers that write nothing.
+func (r *recorder) finish() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+}
+
+// writeTo sends a captured response.
+func (r *recorder) writeTo(rw http.ResponseWriter, req *http.Request) {
+	for h, v := range r.header {
+		rw.Header()[h] = v
+	}
+	rw.WriteHeader(r.status)
+	if req.Method != http.MethodHead {
+		_, _ = rw.Write(r.body.Bytes())
+	}
+}
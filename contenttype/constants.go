@@ -8,15 +8,22 @@ const (
 	TextCSV   = "text/csv"
 	TextPlain = "text/plain"
 
+	TextEventStream = "text/event-stream"
+
 	ApplicationAny = "application/*"
 
 	// application MIME types are sent without charset (since RFC-7231 - see Appendix B)
 
-	ApplicationJSON   = "application/json"
-	ApplicationPDF    = "application/pdf"
-	ApplicationXML    = "application/xml"
-	ApplicationXHTML  = "application/xhtml+xml"
-	ApplicationBinary = "application/octet-stream"
+	ApplicationCBOR    = "application/cbor"
+	ApplicationJSON    = "application/json"
+	ApplicationJSONSeq = "application/json-seq"
+	ApplicationMsgPack = "application/msgpack"
+	ApplicationNDJSON  = "application/x-ndjson"
+	ApplicationPDF     = "application/pdf"
+	ApplicationXML     = "application/xml"
+	ApplicationXHTML   = "application/xhtml+xml"
+	ApplicationYAML    = "application/yaml"
+	ApplicationBinary  = "application/octet-stream"
 
 	// ApplicationForm is for POSTed forms. If you have binary (non-alphanumeric) data
 	// (or a significantly sized payload) to transmit, use multipart/form-data. Otherwise,
@@ -1,7 +1,7 @@
 package data
 
 import (
-	"fmt"
+	"context"
 	"net/http"
 	"time"
 
@@ -9,39 +9,109 @@ import (
 	. "github.com/rickb777/acceptable/headername"
 )
 
+// Chosen holds the decisions made during content negotiation that are relevant for
+// obtaining and rendering the data.
 type Chosen struct {
 	Template string
 	Language string
+	// Charset is the character set of the response. This is for information only, e.g. for
+	// an XML declaration; transcoding happens automatically.
+	Charset string
+	// Request is the request being answered. It gives access to its context (deadlines,
+	// cancellation and request-scoped values), its path values and its headers. It may be nil,
+	// e.g. in tests.
+	Request *http.Request
+	// LastEventID is the ID of the last server-sent event received by a reconnecting client
+	// (see offer.EventStream), so that the supplier can resume from the following event.
+	// It is blank otherwise.
+	LastEventID string
 }
 
-// A Supplier supplies data.
+// Context returns the context of the request, or context.Background if there is no request.
+func (c Chosen) Context() context.Context {
+	if c.Request == nil {
+		return context.Background()
+	}
+	return c.Request.Context()
+}
+
+// A Supplier supplies data. Suppliers that do slow work (e.g. database queries) should use
+// chosen.Context() so that the work is abandoned if the client goes away.
 type Supplier func(chosen Chosen) (any, error)
 
 // Data provides a source for response content. It is optimised for lazy evaluation, avoiding
 // wasted processing as much as possible.
+//
+// Data is not altered by rendering, so it can be constructed once and then used by any number
+// of concurrent requests; the state of each render is held in its Cursor.
 type Data interface {
 	// Meta returns the metadata that will be used to set response headers automatically.
 	// The headers are ETag and Last-Modified.
 	Meta(chosen Chosen) (meta *Metadata, err error)
 
-	// Content returns the data as a value that can be processed by encoders such as "encoding/json"
+	// Content starts obtaining the content for one render, returning a cursor that supplies it.
+	// The cursor must be closed when the render is finished.
+	Content(chosen Chosen) Cursor
+
+	// Headers returns response headers relating to the data (optional)
+	Headers() map[string]string
+}
+
+// Cursor supplies the content of some Data for one render. It is not safe for concurrent use.
+type Cursor interface {
+	// Next returns the data as a value that can be processed by encoders such as "encoding/json"
 	// The returned values are
 	//   - the data itself,
 	//   - a boolean that is true if the data is in chunks and there is more data to follow, and
 	//   - an error if one occurs.
 	// For chunked data, this method will be called repeatedly until the boolean yields false
 	// or an error arises.
-	Content(chosen Chosen) (any, bool, error)
+	Next() (any, bool, error)
 
-	// Headers returns response headers relating to the data (optional)
-	Headers() map[string]string
+	// Close releases any resources held by the cursor, e.g. when the render ends before the
+	// sequence does. It does not close the values returned by Next.
+	Close() error
+}
+
+// Streamer is implemented by the cursors of Value. Unlike Next, NextItem does not look ahead
+// to find whether there is more to follow, so each item is returned as soon as it is available.
+// This suits live streams, e.g. newline-delimited JSON. The boolean is false at the end of the data
+// or when an error arises.
+type Streamer interface {
+	NextItem() (item any, ok bool, err error)
+}
+
+// Stream returns a function that gets each item from a cursor as soon as it is available,
+// using Streamer if the cursor implements it. The boolean is false at the end of the data.
+func Stream(c Cursor) func() (item any, ok bool, err error) {
+	if s, isStreamer := c.(Streamer); isStreamer {
+		return s.NextItem
+	}
+
+	more := true
+	return func() (any, bool, error) {
+		if !more {
+			return nil, false, nil
+		}
+		var item any
+		var err error
+		item, more, err = c.Next()
+		return item, err == nil, err
+	}
 }
 
 // Metadata provides optional entity tag and last modified information about some data. This
-// can be sent with a response such thath the client can make conditional requests in future.
+// can be sent with a response such that the client can make conditional requests in future.
 type Metadata struct {
 	Hash         string    // used as entity tag; blank if not required
+	Weak         bool      // true if the entity tag is a weak validator
 	LastModified time.Time // used for Last-Modified header; zero if not required
+
+	// ContentLength is the number of bytes the processor will write for the content, if
+	// this is known beforehand; zero otherwise. It is used for the Content-Length header of
+	// HEAD responses when no transcoding or compression is needed, which allows them to be
+	// answered without obtaining the content.
+	ContentLength int64
 }
 
 // Of wraps a data value.
@@ -57,7 +127,7 @@ func Of(v any) *Value {
 // If an entity tag is known, the [Value.ETag] method should be used on the result. Likewise,
 // if a last-modified timestamp is known, the [Value.LastModified] method should also be used.
 func Lazy(supplier Supplier) *Value {
-	return &Value{supplier: supplier, chunked: false}
+	return &Value{supplier: supplier}
 }
 
 // Sequence wraps a function that supplies data values in a sequence chunk by chunk. This
@@ -67,10 +137,13 @@ func Lazy(supplier Supplier) *Value {
 // Typical use might be where a response contains many database records that are obtained
 // one by one to avoid the need to cache all results in memory before rendering.
 //
+// The sequence stops with the context's error if the request context is cancelled, e.g.
+// because the client has disconnected (see [Chosen.Context]).
+//
 // If an entity tag is known, the [Value.ETag] method should be used on the result. Likewise,
 // if a last-modified timestamp is known, the [Value.LastModified] method should also be used.
 func Sequence(supplier Supplier) *Value {
-	return &Value{supplier: supplier, chunked: true}
+	return &Value{seq: supplierSequence(supplier)}
 }
 
 //-------------------------------------------------------------------------------------------------
@@ -78,19 +151,22 @@ func Sequence(supplier Supplier) *Value {
 // Value is a simple implementation of Data.
 type Value struct {
 	supplier     Supplier
-	chunked      bool
-	next         any // used for sequence behaviour
+	seq          sequence // used for sequence behaviour
 	etagFn       func(chosen Chosen) (string, error)
 	lastModFn    func(chosen Chosen) (time.Time, error)
 	etag         string
+	weak         bool
+	length       int64
 	lastModified time.Time
 	hdrs         map[string]string
 }
 
 func (v *Value) Meta(chosen Chosen) (meta *Metadata, err error) {
 	meta = &Metadata{
-		Hash:         v.etag,
-		LastModified: v.lastModified,
+		Hash:          v.etag,
+		Weak:          v.weak,
+		ContentLength: v.length,
+		LastModified:  v.lastModified,
 	}
 
 	if v.etagFn != nil {
@@ -107,29 +183,12 @@ func (v *Value) Meta(chosen Chosen) (meta *Metadata, err error) {
 	return meta, err
 }
 
-func (v *Value) Content(chosen Chosen) (result any, more bool, err error) {
-	if v.chunked {
-		return v.chunkedContent(chosen)
-	}
-
-	r, err := v.supplier(chosen)
-	return r, false, err
-}
-
-func (v *Value) chunkedContent(chosen Chosen) (result any, more bool, err error) {
-	if v.next != nil {
-		result = v.next
-		v.next, err = v.supplier(chosen)
-		return result, v.next != nil, err
-	}
-
-	result, err = v.supplier(chosen)
-	if result != nil {
-		// lookahead
-		v.next, err = v.supplier(chosen)
+func (v *Value) Content(chosen Chosen) Cursor {
+	if v.seq != nil {
+		next, stop := v.seq(chosen)
+		return &seqCursor{ctx: chosen.Context(), next: next, stop: stop}
 	}
-
-	return result, result != nil && v.next != nil, err
+	return &lazyCursor{supplier: v.supplier, chosen: chosen}
 }
 
 func (v Value) Headers() map[string]string {
@@ -156,6 +215,16 @@ func (v Value) With(hdr string, value string, others ...string) *Value {
 // returns metadata.
 func (v Value) ETag(hash string) *Value {
 	v.etag = hash
+	v.weak = false
+	return &v
+}
+
+// WeakETag sets a weak entity tag for the content. A weak entity tag is appropriate when the
+// content is semantically equivalent across small changes, or across its representations (e.g. JSON
+// and XML), because it is not used for byte ranges or for If-Match. See RFC-9110 section 8.8.1.
+func (v Value) WeakETag(hash string) *Value {
+	v.etag = hash
+	v.weak = true
 	return &v
 }
 
@@ -167,10 +236,27 @@ func (v Value) LastModified(at time.Time) *Value {
 	return &v
 }
 
+// ContentLength sets the number of bytes that will be written for the content. This is for
+// content such as files and binary data that is written unaltered (see offer.BinaryProcessor).
+// It is used for the Content-Length header of HEAD responses, unless the response is compressed
+// or transcoded; it must be exact.
+func (v Value) ContentLength(n int64) *Value {
+	v.length = n
+	return &v
+}
+
 // ETagUsing lazily sets the entity tag for the content. This allows for conditional requests,
 // possibly avoiding some network traffic.
 func (v Value) ETagUsing(fn func(chosen Chosen) (string, error)) *Value {
 	v.etagFn = fn
+	v.weak = false
+	return &v
+}
+
+// WeakETagUsing lazily sets a weak entity tag for the content (see [Value.WeakETag]).
+func (v Value) WeakETagUsing(fn func(chosen Chosen) (string, error)) *Value {
+	v.etagFn = fn
+	v.weak = true
 	return &v
 }
 
@@ -186,22 +272,40 @@ func (v Value) Expires(at time.Time) *Value {
 	return v.With(Expires, header.FormatHTTPDateTime(at))
 }
 
+// CacheControl sets the Cache-Control header on the response.
+func (v Value) CacheControl(cc header.CacheControl) *Value {
+	return v.With(CacheControl, cc.String())
+}
+
 // MaxAge sets the max-age header on the response. This is used to allow caches to avoid repeating
 // the request until the max age has expired, after which time the resource is considered stale.
+// Use [Value.CacheControl] for other directives.
 func (v Value) MaxAge(max time.Duration) *Value {
-	return v.With(CacheControl, fmt.Sprintf("max-age=%d", max/time.Second))
+	return v.CacheControl(header.CacheControl{MaxAge: header.DeltaSeconds(max)})
 }
 
 // NoCache sets cache control headers to prevent the response being cached.
 func (v Value) NoCache() *Value {
-	return v.With(CacheControl, "no-cache, must-revalidate", Pragma, "no-cache")
+	cc := header.CacheControl{NoCache: true, MustRevalidate: true}
+	return v.CacheControl(cc).With(Pragma, "no-cache")
 }
 
 // ConditionalRequest checks the headers for conditional requests and returns a flag indicating whether
-// content should be rendered or skipped.
+// content should be rendered or skipped. For GET and HEAD requests, the preconditions are evaluated
+// using EvaluatePreconditions.
 //
-// If the returned result value is false, the response has been set to 304-Not Modified, so the
-// response processor does not need to do anything further.
+// If the returned result value is false, the response status has been set to 304-Not Modified or
+// 412-Precondition Failed, so the response processor does not need to do anything further.
+//
+// For other methods, the content is always sent. This is because a handler for an unsafe method
+// (e.g. PUT) has usually made its change already, so it is too late for 412-Precondition Failed
+// (RFC-9110 section 13.2.1). Such handlers must call EvaluatePreconditions themselves before they
+// change anything.
+//
+// The request's Cache-Control (e.g. no-cache or max-age=0) does not alter the outcome here. Those
+// directives are addressed to caches (RFC-9111), so they are honoured by the cache package, which
+// revalidates with the origin instead of using its stored response. At the origin, the current
+// representation is always used, so If-None-Match and If-Modified-Since still apply.
 //
 // Data d must not be nil.
 func ConditionalRequest(rw http.ResponseWriter, req *http.Request, d Data, chosen Chosen) (sendContent bool, err error) {
@@ -214,35 +318,25 @@ func ConditionalRequest(rw http.ResponseWriter, req *http.Request, d Data, chose
 		rw.Header().Set(hn, hv)
 	}
 
-	if meta == nil || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
 		return true, nil
 	}
 
-	sendContent = true
-
-	if meta.Hash != "" {
-		rw.Header().Set(ETag, fmt.Sprintf("%q", meta.Hash))
+	if meta != nil {
+		if meta.Hash != "" {
+			rw.Header().Set(ETag, header.ETag{Hash: meta.Hash, Weak: meta.Weak}.String())
+		}
 
-		ifNoneMatch := header.ETagsOf(req.Header.Get(IfNoneMatch))
-		if ifNoneMatch.WeaklyMatches(meta.Hash) {
-			rw.WriteHeader(http.StatusNotModified)
-			sendContent = false
+		if !meta.LastModified.IsZero() {
+			rw.Header().Set(LastModified, header.FormatHTTPDateTime(meta.LastModified))
 		}
 	}
 
-	if !meta.LastModified.IsZero() {
-		rw.Header().Set(LastModified, header.FormatHTTPDateTime(meta.LastModified))
-
-		if sendContent {
-			ifModifiedSince, e2 := header.ParseHTTPDateTime(req.Header.Get(IfModifiedSince))
-			if e2 == nil && !ifModifiedSince.IsZero() {
-				if meta.LastModified.After(ifModifiedSince) {
-					rw.WriteHeader(http.StatusNotModified)
-					sendContent = false
-				}
-			}
-		}
+	status := EvaluatePreconditions(req, meta)
+	if status != http.StatusOK {
+		rw.WriteHeader(status)
+		return false, nil
 	}
 
-	return sendContent, nil
+	return true, nil
 }
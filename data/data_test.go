@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rickb777/acceptable/header"
 	. "github.com/rickb777/acceptable/headername"
 	"github.com/rickb777/expect"
 )
@@ -36,7 +38,7 @@ func TestLazyValue_should_pass_template_and_language(t *testing.T) {
 		// When ...
 		chosen := Chosen{Template: expectedTemplate, Language: expectedLanguage}
 		send, e1 := ConditionalRequest(w, req, d, chosen)
-		c, more, e2 := d.Content(chosen)
+		c, more, e2 := d.Content(chosen).Next()
 
 		// Then ...
 		expect.Error(e1).Not().ToHaveOccurred(t)
@@ -63,7 +65,7 @@ func TestLazyValue_attaching_eager_metadata(t *testing.T) {
 	// When ...
 	chosen := Chosen{Template: "home.html", Language: "en"}
 	send, e1 := ConditionalRequest(w, req, d, chosen)
-	_, _, e2 := d.Content(chosen)
+	_, _, e2 := d.Content(chosen).Next()
 
 	// Then ...
 	expect.Error(e1).Not().ToHaveOccurred(t)
@@ -93,7 +95,7 @@ func TestLazyValue_attaching_lazy_metadata(t *testing.T) {
 	// When ...
 	chosen := Chosen{Template: "home.html", Language: "en"}
 	send, e1 := ConditionalRequest(w, req, d, chosen)
-	_, _, e2 := d.Content(chosen)
+	_, _, e2 := d.Content(chosen).Next()
 
 	// Then ...
 	expect.Error(e1).Not().ToHaveOccurred(t)
@@ -118,7 +120,7 @@ func TestLazyValue_returning_error(t *testing.T) {
 		// When ...
 		chosen := Chosen{Template: "home.html", Language: "en"}
 		_, e1 := ConditionalRequest(w, req, d, chosen)
-		_, _, e2 := d.Content(chosen)
+		_, _, e2 := d.Content(chosen).Next()
 
 		// Then ...
 		expect.Error(e1).Not().ToHaveOccurred(t)
@@ -127,6 +129,38 @@ func TestLazyValue_returning_error(t *testing.T) {
 	}
 }
 
+func TestSequence_stops_when_context_is_cancelled(t *testing.T) {
+	// Given ...
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/", nil)
+	chosen := Chosen{Request: req}
+
+	n := 0
+	d := Sequence(func(Chosen) (any, error) {
+		n++
+		return n, nil
+	})
+
+	// When ...
+	content := d.Content(chosen)
+	c1, more1, e1 := content.Next()
+	cancel()
+	c2, more2, e2 := content.Next()
+
+	// Then ...
+	expect.Error(e1).Not().ToHaveOccurred(t)
+	expect.Any(c1).ToBe(t, 1)
+	expect.Bool(more1).ToBeTrue(t)
+
+	expect.Error(e2).ToWrap(t, context.Canceled)
+	expect.Any(c2).ToBe(t, nil)
+	expect.Bool(more2).ToBeFalse(t)
+}
+
+func TestChosen_context_without_request(t *testing.T) {
+	expect.Any(Chosen{}.Context()).ToBe(t, context.Background())
+}
+
 //-------------------------------------------------------------------------------------------------
 
 func TestValue_future_expiry(t *testing.T) {
@@ -143,7 +177,7 @@ func TestValue_future_expiry(t *testing.T) {
 	// When ...
 	chosen := Chosen{Template: "home.html", Language: "en"}
 	send, e1 := ConditionalRequest(w, req, d, chosen)
-	_, _, e2 := d.Content(chosen)
+	_, _, e2 := d.Content(chosen).Next()
 
 	// Then ...
 	expect.Error(e1).Not().ToHaveOccurred(t)
@@ -167,7 +201,7 @@ func TestValue_no_cache_and_additional_headers(t *testing.T) {
 	// When ...
 	chosen := Chosen{Template: "home.html", Language: "en"}
 	send, e1 := ConditionalRequest(w, req, d, chosen)
-	_, _, e2 := d.Content(chosen)
+	_, _, e2 := d.Content(chosen).Next()
 
 	// Then ...
 	expect.Error(e1).Not().ToHaveOccurred(t)
@@ -195,7 +229,7 @@ func TestValue_if_none_match_not_modified_get_request(t *testing.T) {
 		// When ...
 		chosen := Chosen{Template: "home.html", Language: "en"}
 		send, e1 := ConditionalRequest(w, req, d, chosen)
-		_, _, e2 := d.Content(chosen)
+		_, _, e2 := d.Content(chosen).Next()
 
 		// Then ...
 		expect.Error(e1).Not().ToHaveOccurred(t)
@@ -219,13 +253,13 @@ func TestValue_if_modified_since_not_modified_get_request(t *testing.T) {
 
 	for _, method := range []string{"GET", "HEAD"} {
 		req, _ := http.NewRequest(method, "/", nil)
-		req.Header.Set(IfModifiedSince, `Wed, 01 Jan 2020 00:00:00 GMT`)
+		req.Header.Set(IfModifiedSince, `Fri, 03 Jan 2020 00:00:00 GMT`)
 		w := httptest.NewRecorder()
 
 		// When ...
 		chosen := Chosen{Template: "home.html", Language: "en"}
 		send, e1 := ConditionalRequest(w, req, d, chosen)
-		_, _, e2 := d.Content(chosen)
+		_, _, e2 := d.Content(chosen).Next()
 
 		// Then ...
 		expect.Error(e1).Not().ToHaveOccurred(t)
@@ -254,7 +288,7 @@ func TestValue_not_modified_put_request(t *testing.T) {
 		// When ...
 		chosen := Chosen{Template: "home.html", Language: "en"}
 		send, e1 := ConditionalRequest(w, req, d, chosen)
-		_, _, e2 := d.Content(chosen)
+		_, _, e2 := d.Content(chosen).Next()
 
 		// Then ...
 		expect.Error(e1).Not().ToHaveOccurred(t)
@@ -269,3 +303,23 @@ func TestValue_not_modified_put_request(t *testing.T) {
 		expect.String(w.Header().Get("Def")).ToBe(t, "true")
 	}
 }
+
+func TestValue_cache_control(t *testing.T) {
+	// Given ...
+	d := Of("foo").CacheControl(header.CacheControl{
+		Public:               true,
+		MaxAge:               header.DeltaSeconds(time.Hour),
+		StaleWhileRevalidate: header.DeltaSeconds(time.Minute),
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	// When ...
+	send, err := ConditionalRequest(w, req, d, Chosen{})
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Bool(send).ToBeTrue(t)
+	expect.String(w.Header().Get(CacheControl)).ToBe(t, "public, max-age=3600, stale-while-revalidate=60")
+}
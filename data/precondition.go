@@ -0,0 +1,97 @@
+package data
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rickb777/acceptable/header"
+	. "github.com/rickb777/acceptable/headername"
+)
+
+// EvaluatePreconditions evaluates the conditional request headers against the metadata of the
+// current representation of the target resource, following the order given in RFC-9110 section
+// 13.2.2. This can be used directly by handlers for unsafe methods such as PUT, so that they
+// only change the resource when the client's precondition holds (i.e. optimistic concurrency
+// control), using the same metadata that their GET handlers produce.
+//
+// The result is
+//
+//   - http.StatusOK if the request method should be performed,
+//   - http.StatusNotModified for a GET or HEAD request whose representation has not changed, or
+//   - http.StatusPreconditionFailed if a precondition failed.
+//
+// A nil meta means that the resource has a current representation but its entity tag and
+// last-modified time are not known.
+func EvaluatePreconditions(req *http.Request, meta *Metadata) int {
+	if meta == nil {
+		meta = &Metadata{}
+	}
+
+	safe := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	// step 1
+	if ifMatch, present := req.Header[IfMatch]; present {
+		if !matches(header.ETagsOf(strings.Join(ifMatch, ", ")), meta, true) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius, ok := parseDateHeader(req, IfUnmodifiedSince); ok {
+		// step 2
+		if !meta.LastModified.IsZero() && truncate(meta.LastModified).After(ius) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	// step 3
+	if ifNoneMatch, present := req.Header[IfNoneMatch]; present {
+		if matches(header.ETagsOf(strings.Join(ifNoneMatch, ", ")), meta, false) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if safe {
+		// step 4
+		if ims, ok := parseDateHeader(req, IfModifiedSince); ok {
+			if !meta.LastModified.IsZero() && !truncate(meta.LastModified).After(ims) {
+				return http.StatusNotModified
+			}
+		}
+	}
+
+	return http.StatusOK
+}
+
+// matches evaluates an If-Match (strong) or If-None-Match (weak) condition. The "*" wildcard
+// matches any current representation. A weak entity tag never matches strongly.
+func matches(etags header.ETags, meta *Metadata, strong bool) bool {
+	for _, e := range etags {
+		if e.Hash == "*" {
+			return true
+		}
+	}
+
+	if meta.Hash == "" {
+		return false
+	}
+
+	if strong {
+		return !meta.Weak && etags.StronglyMatches(meta.Hash)
+	}
+	return etags.WeaklyMatches(meta.Hash)
+}
+
+// parseDateHeader gets an HTTP date header; invalid dates are ignored (RFC-9110 section 13.1.3).
+func parseDateHeader(req *http.Request, name string) (time.Time, bool) {
+	value := req.Header.Get(name)
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := header.ParseHTTPDateTime(value)
+	return t, err == nil && !t.IsZero()
+}
+
+// truncate removes sub-second precision, which cannot be represented in HTTP dates.
+func truncate(t time.Time) time.Time {
+	return t.Truncate(time.Second)
+}
@@ -0,0 +1,172 @@
+package data
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/expect"
+)
+
+func TestEvaluatePreconditions(t *testing.T) {
+	meta := &Metadata{Hash: "hash123", LastModified: t2}
+
+	const before = "Wed, 01 Jan 2020 00:00:00 GMT"
+	const after = "Fri, 03 Jan 2020 00:00:00 GMT"
+
+	cases := []struct {
+		method string
+		hdrs   map[string]string
+		meta   *Metadata
+		exp    int
+	}{
+		{method: "GET", exp: 200},
+		{method: "PUT", exp: 200},
+
+		// If-Match
+		{method: "PUT", hdrs: map[string]string{IfMatch: `"hash123"`}, exp: 200},
+		{method: "PUT", hdrs: map[string]string{IfMatch: `"foo", "hash123"`}, exp: 200},
+		{method: "PUT", hdrs: map[string]string{IfMatch: `"foo"`}, exp: 412},
+		{method: "PUT", hdrs: map[string]string{IfMatch: `W/"hash123"`}, exp: 412}, // strong comparison
+		{method: "PUT", hdrs: map[string]string{IfMatch: `*`}, exp: 200},
+		{method: "PUT", hdrs: map[string]string{IfMatch: `"hash123"`}, meta: &Metadata{}, exp: 412},
+		{method: "GET", hdrs: map[string]string{IfMatch: `"foo"`}, exp: 412},
+		{method: "DELETE", hdrs: map[string]string{IfMatch: `"foo"`}, exp: 412},
+
+		// If-Unmodified-Since
+		{method: "PUT", hdrs: map[string]string{IfUnmodifiedSince: after}, exp: 200},
+		{method: "PUT", hdrs: map[string]string{IfUnmodifiedSince: before}, exp: 412},
+		{method: "PUT", hdrs: map[string]string{IfUnmodifiedSince: "not a date"}, exp: 200},
+		{method: "PUT", hdrs: map[string]string{IfMatch: `"hash123"`, IfUnmodifiedSince: before}, exp: 200}, // If-Match takes precedence
+
+		// If-None-Match
+		{method: "GET", hdrs: map[string]string{IfNoneMatch: `"hash123"`}, exp: 304},
+		{method: "HEAD", hdrs: map[string]string{IfNoneMatch: `W/"hash123"`}, exp: 304}, // weak comparison
+		{method: "GET", hdrs: map[string]string{IfNoneMatch: `"foo"`}, exp: 200},
+		{method: "GET", hdrs: map[string]string{IfNoneMatch: `*`}, exp: 304},
+		{method: "PUT", hdrs: map[string]string{IfNoneMatch: `"hash123"`}, exp: 412},
+		{method: "POST", hdrs: map[string]string{IfNoneMatch: `*`}, exp: 412},
+		{method: "PUT", hdrs: map[string]string{IfNoneMatch: `"foo"`}, exp: 200},
+		{method: "GET", hdrs: map[string]string{IfNoneMatch: `"foo"`, IfModifiedSince: after}, exp: 200}, // If-None-Match takes precedence
+
+		// If-Modified-Since
+		{method: "GET", hdrs: map[string]string{IfModifiedSince: after}, exp: 304},
+		{method: "GET", hdrs: map[string]string{IfModifiedSince: "Thu, 02 Jan 2020 03:04:05 GMT"}, exp: 304},
+		{method: "GET", hdrs: map[string]string{IfModifiedSince: before}, exp: 200},
+		{method: "PUT", hdrs: map[string]string{IfModifiedSince: after}, exp: 200}, // only for GET and HEAD
+		{method: "GET", hdrs: map[string]string{IfModifiedSince: after}, meta: &Metadata{}, exp: 200},
+	}
+
+	for i, c := range cases {
+		// Given ...
+		req, _ := http.NewRequest(c.method, "/", nil)
+		for h, v := range c.hdrs {
+			req.Header.Set(h, v)
+		}
+		m := meta
+		if c.meta != nil {
+			m = c.meta
+		}
+
+		// When ...
+		status := EvaluatePreconditions(req, m)
+
+		// Then ...
+		expect.Number(status).I(fmt.Sprintf("%d: %s %v", i, c.method, c.hdrs)).ToBe(t, c.exp)
+	}
+}
+
+func TestValue_if_match_is_not_evaluated_for_put_request(t *testing.T) {
+	// Given ...
+	d := Of("foo").ETag("hash123")
+
+	req, _ := http.NewRequest("PUT", "/", nil)
+	req.Header.Set(IfMatch, `"hash122"`)
+	w := httptest.NewRecorder()
+
+	// When ...
+	send, err := ConditionalRequest(w, req, d, Chosen{})
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Bool(send).ToBeTrue(t) // the handler should have used EvaluatePreconditions already
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Header().Get(ETag)).ToBe(t, "")
+}
+
+func TestEvaluatePreconditions_with_weak_etag(t *testing.T) {
+	meta := &Metadata{Hash: "hash123", Weak: true}
+
+	cases := []struct {
+		method string
+		hdrs   map[string]string
+		exp    int
+	}{
+		{method: "GET", hdrs: map[string]string{IfNoneMatch: `W/"hash123"`}, exp: 304},
+		{method: "GET", hdrs: map[string]string{IfNoneMatch: `"hash123"`}, exp: 304},
+		{method: "PUT", hdrs: map[string]string{IfMatch: `W/"hash123"`}, exp: 412},
+		{method: "PUT", hdrs: map[string]string{IfMatch: `"hash123"`}, exp: 412},
+		{method: "PUT", hdrs: map[string]string{IfMatch: `*`}, exp: 200},
+	}
+
+	for i, c := range cases {
+		// Given ...
+		req, _ := http.NewRequest(c.method, "/", nil)
+		for h, v := range c.hdrs {
+			req.Header.Set(h, v)
+		}
+
+		// When ...
+		status := EvaluatePreconditions(req, meta)
+
+		// Then ...
+		expect.Number(status).I(fmt.Sprintf("%d: %s %v", i, c.method, c.hdrs)).ToBe(t, c.exp)
+	}
+}
+
+func TestValue_weak_etag_get_request(t *testing.T) {
+	// Given ...
+	d := Of("foo").WeakETag("hash123")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	// When ...
+	send, err := ConditionalRequest(w, req, d, Chosen{})
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Bool(send).ToBeTrue(t)
+	expect.String(w.Header().Get(ETag)).ToBe(t, `W/"hash123"`)
+}
+
+func TestEvaluatePreconditions_not_affected_by_request_cache_control(t *testing.T) {
+	meta := &Metadata{Hash: "hash123", LastModified: t2}
+
+	cases := []struct {
+		hdrs map[string]string
+		exp  int
+	}{
+		{hdrs: map[string]string{CacheControl: "no-cache", IfModifiedSince: "Fri, 03 Jan 2020 00:00:00 GMT"}, exp: 304},
+		{hdrs: map[string]string{CacheControl: "max-age=0", IfModifiedSince: "Fri, 03 Jan 2020 00:00:00 GMT"}, exp: 304},
+		{hdrs: map[string]string{Pragma: "no-cache", IfModifiedSince: "Fri, 03 Jan 2020 00:00:00 GMT"}, exp: 304},
+		{hdrs: map[string]string{CacheControl: "max-age=60", IfModifiedSince: "Fri, 03 Jan 2020 00:00:00 GMT"}, exp: 304},
+		{hdrs: map[string]string{CacheControl: "no-cache", IfNoneMatch: `"hash123"`}, exp: 304},
+	}
+
+	for i, c := range cases {
+		// Given ...
+		req, _ := http.NewRequest("GET", "/", nil)
+		for h, v := range c.hdrs {
+			req.Header.Set(h, v)
+		}
+
+		// When ...
+		status := EvaluatePreconditions(req, meta)
+
+		// Then ...
+		expect.Number(status).I(fmt.Sprintf("%d: %v", i, c.hdrs)).ToBe(t, c.exp)
+	}
+}
@@ -0,0 +1,176 @@
+package data
+
+import (
+	"context"
+	"iter"
+)
+
+// Seq wraps an iterator that supplies data values in a sequence. Each value is rendered
+// as a chunk of the response, in the same way as for [Sequence]. The iterator is started
+// afresh for each response, so a re-usable iterator can be shared by concurrent requests.
+//
+// If an entity tag is known, the [Value.ETag] method should be used on the result. Likewise,
+// if a last-modified timestamp is known, the [Value.LastModified] method should also be used.
+func Seq[T any](seq iter.Seq[T]) *Value {
+	return Seq2(func(yield func(T, error) bool) {
+		for v := range seq {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	})
+}
+
+// Seq2 wraps an iterator that supplies data values in a sequence, along with errors. The
+// sequence stops at the first error, which is returned from the render. Otherwise, this
+// is the same as [Seq].
+func Seq2[T any](seq iter.Seq2[T, error]) *Value {
+	return &Value{seq: func(Chosen) (func() (any, bool, error), func()) {
+		next, stop := iter.Pull2(seq)
+		return func() (any, bool, error) {
+			v, err, ok := next()
+			if !ok || err != nil {
+				return nil, false, err
+			}
+			return v, true, nil
+		}, stop
+	}}
+}
+
+// Chan wraps a channel that supplies data values in a sequence. The sequence ends when the
+// channel is closed. Each value is rendered as a chunk of the response, in the same way as
+// for [Sequence]. Waiting for values stops if the request context is cancelled.
+//
+// Unlike iterators, a channel is consumed by the first response that reads it.
+func Chan[T any](ch <-chan T) *Value {
+	return &Value{seq: func(chosen Chosen) (func() (any, bool, error), func()) {
+		ctx := chosen.Context()
+		return func() (any, bool, error) {
+			select {
+			case v, ok := <-ch:
+				if !ok {
+					return nil, false, nil
+				}
+				return v, true, nil
+			case <-ctx.Done():
+				return nil, false, ctx.Err()
+			}
+		}, func() {}
+	}}
+}
+
+// supplierSequence adapts a Supplier for use by [Sequence]; the sequence ends when the
+// supplier returns nil.
+func supplierSequence(supplier Supplier) sequence {
+	return func(chosen Chosen) (func() (any, bool, error), func()) {
+		return func() (any, bool, error) {
+			v, err := supplier(chosen)
+			return v, v != nil && err == nil, err
+		}, func() {}
+	}
+}
+
+//-------------------------------------------------------------------------------------------------
+
+// sequence begins a new iteration for one render; next returns false at the end of the
+// sequence and stop releases any resources.
+type sequence func(Chosen) (next func() (any, bool, error), stop func())
+
+// lazyCursor supplies a single value.
+type lazyCursor struct {
+	supplier Supplier
+	chosen   Chosen
+	done     bool
+}
+
+func (c *lazyCursor) Next() (any, bool, error) {
+	r, err := c.supplier(c.chosen)
+	return r, false, err
+}
+
+func (c *lazyCursor) NextItem() (any, bool, error) {
+	if c.done {
+		return nil, false, nil
+	}
+	c.done = true
+	r, err := c.supplier(c.chosen)
+	return r, err == nil, err
+}
+
+func (c *lazyCursor) Close() error {
+	return nil
+}
+
+// seqCursor supplies the values of a sequence. Next looks one item ahead so that it can
+// report whether there is more to follow.
+type seqCursor struct {
+	ctx     context.Context
+	next    func() (any, bool, error)
+	stop    func()
+	done    bool
+	pending bool // true when head holds the next item
+	head    any
+}
+
+func (c *seqCursor) Next() (any, bool, error) {
+	item, more, err := c.advance()
+	if !more {
+		_ = c.Close()
+	}
+	return item, more, err
+}
+
+// advance returns the current item and whether there are more to follow.
+func (c *seqCursor) advance() (any, bool, error) {
+	if c.done {
+		return nil, false, nil
+	}
+
+	// stop iterating if the client has gone away
+	if err := c.ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if !c.pending {
+		head, ok, err := c.next()
+		if !ok {
+			return nil, false, err
+		}
+		c.head = head
+	}
+
+	item := c.head
+	head, ok, err := c.next()
+	c.head, c.pending = head, ok
+	return item, ok, err
+}
+
+func (c *seqCursor) NextItem() (any, bool, error) {
+	if c.done {
+		return nil, false, nil
+	}
+
+	if err := c.ctx.Err(); err != nil {
+		_ = c.Close()
+		return nil, false, err
+	}
+
+	if c.pending {
+		c.pending = false
+		return c.head, true, nil
+	}
+
+	item, ok, err := c.next()
+	if !ok {
+		_ = c.Close()
+	}
+	return item, ok, err
+}
+
+func (c *seqCursor) Close() error {
+	if !c.done {
+		c.done = true
+		c.stop()
+	}
+	return nil
+}
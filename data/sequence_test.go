@@ -0,0 +1,225 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/rickb777/expect"
+)
+
+// drain gets all the content, stopping at the first error.
+func drain(d Data, chosen Chosen) ([]any, error) {
+	content := d.Content(chosen)
+	defer content.Close()
+
+	var items []any
+	for {
+		item, more, err := content.Next()
+		if err != nil {
+			return items, err
+		}
+		if item != nil {
+			items = append(items, item)
+		}
+		if !more {
+			return items, nil
+		}
+	}
+}
+
+func TestSeq(t *testing.T) {
+	cases := []struct {
+		values   []int
+		expected []any
+	}{
+		{values: nil, expected: nil},
+		{values: []int{1}, expected: []any{1}},
+		{values: []int{1, 2, 3}, expected: []any{1, 2, 3}},
+	}
+
+	for i, c := range cases {
+		// Given ...
+		d := Seq(slices.Values(c.values))
+
+		// When ...
+		items, err := drain(d, Chosen{})
+
+		// Then ...
+		expect.Error(err).I(i).Not().ToHaveOccurred(t)
+		expect.Slice(items).I(i).ToBe(t, c.expected...)
+	}
+}
+
+func TestSeq2_stops_at_error(t *testing.T) {
+	// Given ...
+	d := Seq2(func(yield func(string, error) bool) {
+		_ = yield("a", nil) && yield("b", nil) && yield("", errors.New("broken")) && yield("c", nil)
+	})
+
+	// When ...
+	items, err := drain(d, Chosen{})
+
+	// Then ...
+	expect.Error(err).ToContain(t, "broken")
+	expect.Slice(items).ToBe(t, "a")
+}
+
+func TestChan(t *testing.T) {
+	// Given ...
+	ch := make(chan string, 3)
+	ch <- "a"
+	ch <- "b"
+	close(ch)
+	d := Chan(ch)
+
+	// When ...
+	items, err := drain(d, Chosen{})
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Slice(items).ToBe(t, "a", "b")
+}
+
+func TestChan_stops_when_context_is_cancelled(t *testing.T) {
+	// Given ...
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/", nil)
+	ch := make(chan int) // never closed
+	d := Chan(ch)
+
+	go func() {
+		ch <- 1
+		ch <- 2
+		cancel()
+	}()
+
+	// When ...
+	items, err := drain(d, Chosen{Request: req})
+
+	// Then ...
+	expect.Error(err).ToWrap(t, context.Canceled)
+	expect.Slice(items).ToBe(t, 1)
+}
+
+func TestSequences_are_independent_per_cursor(t *testing.T) {
+	// Given ...
+	r1, _ := http.NewRequest("GET", "/1", nil)
+	r2, _ := http.NewRequest("GET", "/2", nil)
+	c1 := Chosen{Request: r1}
+	c2 := Chosen{Request: r2}
+
+	n := map[*http.Request]int{}
+	values := []*Value{
+		Seq(slices.Values([]int{1, 2, 3})),
+		Seq2(func(yield func(int, error) bool) {
+			_ = yield(1, nil) && yield(2, nil) && yield(3, nil)
+		}),
+		Sequence(func(chosen Chosen) (any, error) {
+			n[chosen.Request]++
+			if n[chosen.Request] > 3 {
+				return nil, nil
+			}
+			return n[chosen.Request], nil
+		}),
+	}
+
+	for i, d := range values {
+		// When ...
+		cursor1 := d.Content(c1)
+		cursor2 := d.Content(c2)
+
+		var got1, got2 []any
+		for more := true; more; {
+			var item any
+			item, more, _ = cursor1.Next()
+			got1 = append(got1, item)
+			item, _, _ = cursor2.Next()
+			got2 = append(got2, item)
+		}
+
+		// Then ...
+		expect.Slice(got1).I(i).ToBe(t, 1, 2, 3)
+		expect.Slice(got2).I(i).ToBe(t, 1, 2, 3)
+	}
+}
+
+func TestSeq_cursor_closed_early(t *testing.T) {
+	// Given ...
+	stopped := false
+	d := Seq(func(yield func(int) bool) {
+		defer func() { stopped = true }()
+		for i := 1; yield(i); i++ {
+		}
+	})
+
+	content := d.Content(Chosen{})
+	item, more, err := content.Next()
+
+	// When ...
+	e2 := content.Close()
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Any(item).ToBe(t, 1)
+	expect.Bool(more).ToBeTrue(t)
+	expect.Error(e2).Not().ToHaveOccurred(t)
+	expect.Bool(stopped).ToBeTrue(t)
+}
+
+func TestStream_does_not_wait_for_the_following_item(t *testing.T) {
+	// Given ...
+	ch := make(chan string, 1)
+	ch <- "a"
+	content := Chan(ch).Content(Chosen{})
+	next := Stream(content)
+
+	// When ...
+	item, ok, err := next() // this would block if it looked ahead
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Any(item).ToBe(t, "a")
+	expect.Bool(ok).ToBeTrue(t)
+
+	// When ...
+	close(ch)
+	item, ok, err = next()
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Any(item).ToBeNil(t)
+	expect.Bool(ok).ToBeFalse(t)
+}
+
+func TestStream_without_streamer(t *testing.T) {
+	// Given ...
+	content := &prefetchedCursor{items: []any{"a", "b"}}
+	next := Stream(content)
+
+	// When ...
+	var items []any
+	for item, ok, _ := next(); ok; item, ok, _ = next() {
+		items = append(items, item)
+	}
+
+	// Then ...
+	expect.Slice(items).ToBe(t, "a", "b")
+}
+
+// prefetchedCursor is a Cursor that is not a Streamer.
+type prefetchedCursor struct {
+	items []any
+}
+
+func (c *prefetchedCursor) Next() (any, bool, error) {
+	item := c.items[0]
+	c.items = c.items[1:]
+	return item, len(c.items) > 0, nil
+}
+
+func (c *prefetchedCursor) Close() error {
+	return nil
+}
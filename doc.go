@@ -5,6 +5,8 @@
 //
 // # Subpackages
 //
+// * cache - middleware that caches rendered responses, keyed by their negotiated variants
+//
 // * contenttype, headername - bundles of useful constants
 //
 // * data - for holding response data & metadata prior to rendering the response, also allowing lazy evaluation
@@ -45,8 +47,28 @@
 // Note that contenttype.TextAny is "text/*" and will typically return "text/plain"; contenttype.Any is "*/*"
 // and will likewise return "application/octet-stream".
 //
+// Offers can have media type parameters, e.g. offer.Of(p, "application/json;version=2"). Parameters in the
+// Accept header must then be matched by the offer, and a media range with matching parameters takes precedence
+// over a bare media type (see RFC-9110 section 12.5.1). The matched offer's parameters are included in the
+// Content-Type response header.
+//
+// When several offers are acceptable, they are ranked by the quality values in the Accept header multiplied by
+// each offer's source quality (see offer.Offer.WithSourceQuality), with the order of offers breaking any ties.
+// So, for example, a lossy CSV representation can be given a lower source quality than JSON, so that JSON is
+// preferred whenever the client rates both equally.
+//
+// RankedRequestMatches lists every acceptable combination of offer and language, best first, each with its
+// effective quality. This can be used, for example, to build Link rel=alternate headers, or to fall back to the
+// next representation when rendering fails.
+//
+// The quality of each offer is taken from the most specific media range that matches it (RFC-9110 section 12.5.1).
+// So "Accept: text/*;q=0, */*" excludes a text/csv offer, whereas "Accept: text/html;q=0, text/*" excludes
+// text/html but allows text/plain.
+//
 // Each offer will (usually) have a suitable offer.Processor, which is a rendering function. Several are
-// provided (for JSON, XML etc), but you can also provide your own.
+// provided (for JSON, XML, YAML, CBOR, MessagePack etc), but you can also provide your own. The YAML, CBOR
+// and MessagePack processors need an encoder from a library of your choice (see offer.NewYAMLEncoder,
+// offer.NewCBOREncoder and offer.NewMsgPackEncoder).
 //
 // Also, the templates sub-package provides Go template support.
 //
@@ -58,6 +80,11 @@
 // if you specify "en-GB", it only matches "en-GB" and "en-GB-*", but won't match "en-US" or even "en".
 // (This implements the basic filtering language matching algorithm defined in https://tools.ietf.org/html/rfc4647.)
 //
+// Alternatively, set Negotiator.LanguageMatcher to BestFit to use the language matcher from golang.org/x/text/language
+// (the package-level LanguageMatcher provides the default). This scores the offered languages by linguistic distance,
+// so for example "zh-TW" will match "zh-Hant" and "es-419" will match "es-MX". The chosen language is then the
+// canonical form of the offered tag, and the confidence of the match is available in the resulting offer.Match.
+//
 // If your data doesn't need to specify a language, the With method should simply use the "*" wildcard instead. For
 // example, myOffer.With(data, "*") attaches data to myOffer and doesn't restrict the offer to any particular language.
 //
@@ -66,6 +93,30 @@
 // failed; RenderBestMatch will do this by picking the first language listed as a fallback, so the catch-all case
 // is only necessary if its data is different to that of the first case.
 //
+// # Independent configuration
+//
+// Behaviour is configured by package-level settings such as NoMatchAccepted, LanguageMatcher, offer.GZIPLevel and
+// templates.ReloadOnTheFly. Where different parts of a program (or parallel tests) need different settings, use a
+// Negotiator instead. This holds all the settings, including an offer.Config and a templates.Config for
+// constructing offers, and has BestRequestMatch, RankedRequestMatches and RenderBestMatch methods.
+//
+//	n := acceptable.DefaultNegotiator()
+//	n.LanguageMatcher = acceptable.BestFit
+//	n.Offers.GZIPLevel = offer.NoCompression
+//	err := n.RenderBestMatch(response, request, n.Offers.JSON().With(en, "en"))
+//
+// # Diagnostics
+//
+// The decisions made during content negotiation can be observed by attaching a Trace to the request context.
+// After negotiation, it lists which offers were excluded, which pass (exact or near) matched each offer, whether
+// language matching fell back to the default language, which charset was chosen and whether a 406 fallback
+// offer was used. A Trace can be logged with log/slog.
+//
+//	trace := &acceptable.Trace{}
+//	req = req.WithContext(acceptable.WithTrace(req.Context(), trace))
+//	err := acceptable.RenderBestMatch(response, req, offer1, offer2)
+//	slog.Debug("content negotiation", "trace", trace)
+//
 // # Providing response data
 //
 // The response data (en and fr above) can be structs, slices, maps, or other values that the rendering processors
@@ -90,12 +141,60 @@
 // The metadata can also carry the last-modified timestamp of the data, if this is known. When present, this becomes the
 // Last-Modified header and is checked on subsequent requests using the If-Modified-Since.
 //
+// All the conditional request headers (If-Match, If-None-Match, If-Modified-Since and If-Unmodified-Since) are
+// evaluated in the order given in RFC-9110 section 13.2.2, giving 304-Not Modified or 412-Precondition Failed as
+// needed. This applies to GET and HEAD requests only. Handlers for unsafe methods such as PUT must use
+// data.EvaluatePreconditions with the same metadata before changing a resource, which provides optimistic
+// concurrency control; RenderBestMatch can then send the result without checking the preconditions again.
+//
+// Byte range requests (e.g. for resuming large downloads) are supported when RespondWith.AcceptRanges is set. Range
+// and If-Range are handled by http.ServeContent, which gives 206-Partial Content (using multipart/byteranges for
+// multiple ranges). Data that is an io.ReadSeeker is served directly for offers with RawContent set (such as
+// offer.ImagePNG); otherwise the rendered output is buffered first.
+//
+// Entity tags can also be computed automatically by setting RespondWith.AutoETag. The rendered response is buffered and
+// hashed, along with its content type, language and content coding, so that each representation has its own strong
+// entity tag (e.g. JSON and XML of the same data differ). This happens only when the data's metadata has no hash.
+//
+// Weak entity tags can be declared using data.Value.WeakETag; these are suitable when all the representations are
+// semantically equivalent. Weak tags are used only for If-None-Match. Strong entity tags from the data's metadata
+// can be made specific to each representation by setting RespondWith.RepresentationETags.
+//
+// HEAD requests are answered without rendering the response, so the data content is not obtained (unless needed
+// for RespondWith.AutoETag, or the offer's processor may compress for itself). If the content length is known
+// beforehand, data.Value.ContentLength declares it so that the Content-Length header of HEAD responses can be set.
+//
 // The template and language parameters are used for templated/web content data; otherwise they are ignored.
+// The supplier is also given the request (data.Chosen.Request), so it can use its context, path values etc.
 //
 // Sequences of data can also be produced. This is done with data.Sequence() and this takes the same supplier function
 // as used by data.Lazy(). The difference is that, in a sequence, the supplier function will be called repeatedly
 // until its result value is nil. All the values will be streamed in the response (how this is done depends on
-// the rendering processor.
+// the rendering processor. The sequence stops if the request context is cancelled, e.g. when the client disconnects.
+// Sequences can also be produced from iterators and channels using data.Seq, data.Seq2 and data.Chan.
+//
+// For streaming consumers, offer.NDJSON() writes each item of a sequence as a line of JSON, flushing the response
+// as it goes; offer.JSONSeq() does the same using RFC-7464 framing (application/json-seq). Likewise,
+// offer.EventStream() writes each item as a server-sent event (text/event-stream); the items can be offer.Event
+// values that provide the event ID, name and retry interval.
+//
+// Processors obtain the content using a data.Cursor, which holds the state of each render. So offers and their data
+// are not altered by rendering; they can be constructed once (e.g. at startup) and used by concurrent requests.
+//
+// # Compression
+//
+// The content coding of each response is negotiated using the Accept-Encoding header, including its quality values
+// and "*" (see RFC-9110 section 12.5.3). Offers such as offer.JSON() compress their responses using the level in
+// offer.GZIPLevel; others can do so using Offer.WithCompressionLevel. The gzip and deflate codings are built in;
+// others such as br and zstd can be added to offer.Compressors (or to offer.Config.Compressors for independent
+// settings). If the client refuses the identity coding and no other coding is available, the response will be
+// 406-Not Acceptable. Offers constructed using offer.Of are not excluded in this way when another coding is
+// acceptable, because their processors might compress for themselves (e.g. via offer.EncodingProcessor); see
+// Offer.IdentityProcessor.
+//
+// Processors write to an offer.ResponseWriter, which is a pipeline that transcodes the character set (see below),
+// then compresses, then writes the response. It supports http.Flusher, so streamed responses can be flushed as they
+// are written.
 //
 // # Character set transcoding
 //
@@ -105,8 +204,14 @@
 // that the Accept-Charset content negotiation can be implemented. This depends on finding an encoder in
 // golang.org/x/text/encoding/htmlindex (this has an extensive list, however no other encoders are supported).
 //
+// Each offer can declare the character sets it supports using Offer.WithCharsets; otherwise it supports UTF-8 and any
+// character set known to htmlindex. The character set with the highest quality in Accept-Charset is chosen; if none
+// is acceptable, the response will be 406-Not Acceptable (or the Handle406As fallback offer is used). Character
+// set negotiation applies only to textual media types. The XML processor adds an XML declaration stating the encoding
+// whenever the character set is not UTF-8.
+//
 // Whenever possible, responses will be UTF-8. Not only is this strongly recommended, it also avoids any transcoding
 // processing overhead. It means for example that "Accept-Charset: iso-8859-1, utf-8" will ignore the iso-8859-1
-// preference because it can use UTF-8. Conversely, "Accept-Charset: iso-8859-1" will always have to transcode into
+// preference because UTF-8 is equally acceptable. Conversely, "Accept-Charset: iso-8859-1" will always have to transcode into
 // ISO-8859-1 because there is no UTF-8 option.
 package acceptable
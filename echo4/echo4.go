@@ -37,3 +37,11 @@ func RenderBestMatch(c echo.Context, statusCode int, template string, available
 func BestRequestMatch(c echo.Context, available ...offer.Offer) *offer.Match {
 	return acceptable.BestRequestMatch(c.Request(), available...)
 }
+
+// RankedRequestMatches finds all the acceptable combinations of the available offers and
+// their languages, ranked with the best match first. Each match holds its effective quality.
+//
+// Whenever the result is empty, the response should be 406-Not Acceptable.
+func RankedRequestMatches(c echo.Context, available ...offer.Offer) []*offer.Match {
+	return acceptable.RankedRequestMatches(c.Request(), available...)
+}
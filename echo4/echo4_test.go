@@ -33,7 +33,7 @@ func TestBestRequestMatch_should_match_best_offer(t *testing.T) {
 	match := echo4.BestRequestMatch(ec, oa, ob, oc, od, oe)
 
 	// Then ...
-	expect.Value(match.Data.Content(dpkg.Chosen{Language: "en"})).ToBe(t, "hello")
+	expect.Value(match.Data.Content(dpkg.Chosen{Language: "en"}).Next()).ToBe(t, "hello")
 	expect.Map(w.Header()).ToHaveLength(t, 0)
 }
 
@@ -83,3 +83,24 @@ func TestRenderBestMatch_should_give_JSON_response_for_ajax_requests(t *testing.
 	expect.String(w.Header().Get(ContentType)).ToBe(t, "application/json")
 	expect.String(w.Header().Get(ContentLanguage)).ToBe(t, "en")
 }
+
+func TestRankedRequestMatches_should_list_acceptable_offers(t *testing.T) {
+	// Given ...
+	oa := offer.Of(offer.TXTProcessor(0), TextPlain).With("foo", "en")
+	oc := offer.Of(offer.JSONProcessor(0), ApplicationJSON).With("hello", "en")
+	od := offer.Of(offer.XMLProcessor(0, "x"), ApplicationXML).With("zzz", "en")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(Accept, "application/json, text/plain;q=0.5, application/xml;q=0")
+	w := httptest.NewRecorder()
+	ec := e.NewContext(req, w)
+
+	// When ...
+	ranked := echo4.RankedRequestMatches(ec, oa, oc, od)
+
+	// Then ...
+	expect.Slice(ranked).ToHaveLength(t, 2)
+	expect.String(ranked[0].MediaType).ToBe(t, ApplicationJSON)
+	expect.String(ranked[1].MediaType).ToBe(t, TextPlain)
+}
@@ -0,0 +1,121 @@
+package acceptable
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+
+	dpkg "github.com/rickb777/acceptable/data"
+	"github.com/rickb777/acceptable/headername"
+	offerpkg "github.com/rickb777/acceptable/offer"
+)
+
+// knownDataFor gets the metadata of the matched data once only. It also decides how the entity
+// tag will be derived from the representation. When the data has no entity tag, it may be computed
+// from the rendered response (see AutoETag); otherwise, a strong tag may be made specific to the
+// representation (see RepresentationETags).
+func (ctx RespondWith) knownDataFor(req *http.Request, best *offerpkg.Match, chosen dpkg.Chosen) (*knownData, error) {
+	if best.Data == nil || best.StatusCodeOverride != 0 {
+		return nil, nil
+	}
+
+	meta, err := best.Data.Meta(chosen)
+	if err != nil {
+		return nil, err
+	}
+
+	if meta == nil {
+		meta = &dpkg.Metadata{}
+	}
+
+	known := &knownData{Data: best.Data, meta: *meta}
+
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		switch {
+		case meta.Hash == "":
+			known.fromContent = ctx.AutoETag
+		case !meta.Weak:
+			// weak entity tags apply to all representations
+			known.representationSpecific = ctx.RepresentationETags
+		}
+	}
+
+	return known, nil
+}
+
+// deriveETag alters the entity tag according to the representation, if required. The response
+// headers must have been set already.
+func (d *knownData) deriveETag(hdr http.Header, buf *bytes.Buffer) {
+	switch {
+	case d.fromContent:
+		d.meta.Hash = representationETag(hdr, buf.Bytes())
+	case d.representationSpecific:
+		d.meta.Hash = representationSpecificETag(hdr, d.meta.Hash)
+	}
+}
+
+// representationETag computes a strong entity tag from the response headers that distinguish
+// one representation from another, along with the response content.
+func representationETag(hdr http.Header, content []byte) string {
+	h := sha256.New()
+	writeRepresentation(h, hdr)
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// representationSpecificETag derives a strong entity tag from the entity tag of some data
+// along with the response headers that distinguish one representation from another.
+func representationSpecificETag(hdr http.Header, hash string) string {
+	h := sha256.New()
+	writeRepresentation(h, hdr)
+	return hash + "-" + hex.EncodeToString(h.Sum(nil)[:4])
+}
+
+func writeRepresentation(w io.Writer, hdr http.Header) {
+	for _, name := range []string{headername.ContentType, headername.ContentLanguage, headername.ContentEncoding} {
+		io.WriteString(w, hdr.Get(name))
+		w.Write([]byte{0})
+	}
+}
+
+// writeBuffered writes a buffered response, which may be a range request. The body is
+// omitted for HEAD requests.
+func (ctx RespondWith) writeBuffered(rw http.ResponseWriter, req *http.Request, buf *bytes.Buffer, ranges, rangeRequest bool) error {
+	if rangeRequest {
+		serveRanges(rw, req, bytes.NewReader(buf.Bytes()))
+		return nil
+	}
+
+	if ranges {
+		rw.Header().Set(headername.AcceptRanges, "bytes")
+	}
+
+	rw.Header().Set(headername.ContentLength, strconv.Itoa(buf.Len()))
+
+	if ctx.StatusCode > 0 {
+		rw.WriteHeader(ctx.StatusCode)
+	}
+
+	if req.Method == http.MethodHead {
+		return nil
+	}
+
+	_, err := rw.Write(buf.Bytes())
+	return err
+}
+
+// knownData is data whose metadata has been obtained already.
+type knownData struct {
+	dpkg.Data
+	meta                   dpkg.Metadata
+	fromContent            bool // true when the entity tag is computed from the rendered content
+	representationSpecific bool // true when the entity tag is made specific to the representation
+}
+
+func (d *knownData) Meta(dpkg.Chosen) (*dpkg.Metadata, error) {
+	meta := d.meta
+	return &meta, nil
+}
@@ -0,0 +1,152 @@
+package acceptable_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/rickb777/acceptable"
+	"github.com/rickb777/acceptable/data"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+type point struct {
+	X, Y int
+}
+
+func renderAutoETag(t *testing.T, hdrs map[string]string, available ...offer.Offer) *httptest.ResponseRecorder {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/", nil)
+	for h, v := range hdrs {
+		req.Header.Set(h, v)
+	}
+	w := httptest.NewRecorder()
+
+	err := acceptable.RespondWith{AutoETag: true, AcceptRanges: true}.RenderBestMatch(w, req, available...)
+
+	expect.Error(err).Not().ToHaveOccurred(t)
+	return w
+}
+
+func Test_auto_etag_should_give_304_when_if_none_match_matches(t *testing.T) {
+	// Given ...
+	a := offer.JSON().With(point{X: 1, Y: 2}, "*")
+	first := renderAutoETag(t, nil, a)
+	etag := first.Header().Get(ETag)
+
+	// When ...
+	w := renderAutoETag(t, map[string]string{IfNoneMatch: etag}, a)
+
+	// Then ...
+	expect.Number(first.Code).ToBe(t, 200)
+	expect.String(etag).ToMatch(t, regexp.MustCompile(`^"[0-9a-f]{32}"$`))
+	expect.String(first.Header().Get(ContentLength)).ToBe(t, "14")
+	expect.String(first.Body.String()).ToBe(t, `{"X":1,"Y":2}`+"\n")
+
+	expect.Number(w.Code).ToBe(t, 304)
+	expect.String(w.Header().Get(ETag)).ToBe(t, etag)
+	expect.String(w.Body.String()).ToBe(t, "")
+}
+
+func Test_auto_etag_should_differ_between_representations(t *testing.T) {
+	// Given ...
+	v := point{X: 1, Y: 2}
+	a := offer.JSON().With(v, "*")
+	b := offer.XML("point").With(v, "*")
+
+	// When ...
+	asJSON := renderAutoETag(t, map[string]string{Accept: "application/json"}, a, b)
+	asXML := renderAutoETag(t, map[string]string{Accept: "application/xml"}, a, b)
+	asGzip := renderAutoETag(t, map[string]string{Accept: "application/json", AcceptEncoding: "gzip"}, a, b)
+
+	// Then ...
+	expect.String(asJSON.Header().Get(ETag)).Not().ToBe(t, "")
+	expect.String(asXML.Header().Get(ETag)).Not().ToBe(t, asJSON.Header().Get(ETag))
+	expect.String(asGzip.Header().Get(ETag)).Not().ToBe(t, asJSON.Header().Get(ETag))
+}
+
+func Test_auto_etag_should_not_replace_etag_of_data(t *testing.T) {
+	// Given ...
+	a := offer.JSON().With(data.Of(point{X: 1, Y: 2}).ETag("abc"), "*")
+
+	// When ...
+	w := renderAutoETag(t, nil, a)
+
+	// Then ...
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Header().Get(ETag)).ToBe(t, `"abc"`)
+	expect.String(w.Body.String()).ToBe(t, `{"X":1,"Y":2}`+"\n")
+}
+
+func Test_auto_etag_should_validate_if_range(t *testing.T) {
+	// Given ...
+	a := offer.TextPlain().With("Hello world", "*")
+	etag := renderAutoETag(t, nil, a).Header().Get(ETag)
+
+	// When ...
+	w := renderAutoETag(t, map[string]string{Range: "bytes=0-4", IfRange: etag}, a)
+
+	// Then ...
+	expect.Number(w.Code).ToBe(t, 206)
+	expect.String(w.Body.String()).ToBe(t, "Hello")
+}
+
+func renderRepresentationETags(t *testing.T, hdrs map[string]string, available ...offer.Offer) *httptest.ResponseRecorder {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/", nil)
+	for h, v := range hdrs {
+		req.Header.Set(h, v)
+	}
+	w := httptest.NewRecorder()
+
+	err := acceptable.RespondWith{RepresentationETags: true, AcceptRanges: true}.RenderBestMatch(w, req, available...)
+
+	expect.Error(err).Not().ToHaveOccurred(t)
+	return w
+}
+
+func Test_representation_etags_should_differ_between_representations(t *testing.T) {
+	// Given ...
+	d := data.Of(point{X: 1, Y: 2}).ETag("abc")
+	a := offer.JSON().With(d, "*")
+	b := offer.XML("point").With(d, "*")
+
+	// When ...
+	asJSON := renderRepresentationETags(t, map[string]string{Accept: "application/json"}, a, b)
+	asXML := renderRepresentationETags(t, map[string]string{Accept: "application/xml"}, a, b)
+	asGzip := renderRepresentationETags(t, map[string]string{Accept: "application/json", AcceptEncoding: "gzip"}, a, b)
+
+	// Then ...
+	expect.String(asJSON.Header().Get(ETag)).ToMatch(t, regexp.MustCompile(`^"abc-[0-9a-f]{8}"$`))
+	expect.String(asXML.Header().Get(ETag)).Not().ToBe(t, asJSON.Header().Get(ETag))
+	expect.String(asGzip.Header().Get(ETag)).Not().ToBe(t, asJSON.Header().Get(ETag))
+}
+
+func Test_representation_etags_should_give_304_when_if_none_match_matches(t *testing.T) {
+	// Given ...
+	a := offer.JSON().With(data.Of(point{X: 1, Y: 2}).ETag("abc"), "*")
+	etag := renderRepresentationETags(t, nil, a).Header().Get(ETag)
+
+	// When ...
+	w := renderRepresentationETags(t, map[string]string{IfNoneMatch: etag}, a)
+
+	// Then ...
+	expect.Number(w.Code).ToBe(t, 304)
+	expect.String(w.Header().Get(ETag)).ToBe(t, etag)
+}
+
+func Test_representation_etags_should_not_alter_weak_etag(t *testing.T) {
+	// Given ...
+	a := offer.TextPlain().With(data.Of("Hello world").WeakETag("abc"), "*")
+
+	// When ...
+	w := renderRepresentationETags(t, map[string]string{Range: "bytes=0-4", IfRange: `W/"abc"`}, a)
+
+	// Then ...
+	expect.String(w.Header().Get(ETag)).ToBe(t, `W/"abc"`)
+	expect.Number(w.Code).ToBe(t, 200) // a weak entity tag cannot validate If-Range
+	expect.String(w.Body.String()).ToBe(t, "Hello world\n")
+}
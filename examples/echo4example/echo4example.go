@@ -1,7 +1,7 @@
 package main
 
 import (
-	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -32,9 +32,7 @@ import (
 //     * gets Russian as HTML using the page home.html
 
 func main() {
-	acceptable.Debug = func(msg string, args ...any) {
-		fmt.Printf(msg, args...)
-	}
+	slog.SetLogLoggerLevel(slog.LevelDebug)
 
 	templates.ReloadOnTheFly = true // development mode
 
@@ -61,6 +59,11 @@ func hello(c echo.Context) error {
 
 	template := c.Request().URL.String()[1:]
 
+	// record the content negotiation decisions, for diagnostic logging
+	trace := &acceptable.Trace{}
+	c.SetRequest(c.Request().WithContext(acceptable.WithTrace(c.Request().Context(), trace)))
+	defer slog.Debug("content negotiation", "trace", trace)
+
 	return echo4.RenderBestMatch(c, 200, template,
 		offer.JSON("  ").
 			With(lazyEn, "EN").With(examples.FR, "FR").With(examples.ES, "ES").With(examples.RU, "RU"),
@@ -1,8 +1,8 @@
 package main
 
 import (
-	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -31,9 +31,7 @@ import (
 //     * gets Russian as HTML using the page home.html
 
 func main() {
-	acceptable.Debug = func(msg string, args ...any) {
-		fmt.Printf(msg, args...)
-	}
+	slog.SetLogLoggerLevel(slog.LevelDebug)
 
 	templates.ReloadOnTheFly = true // development mode
 
@@ -77,6 +75,11 @@ func hello(rw http.ResponseWriter, req *http.Request) {
 
 	template := req.URL.String()[1:]
 
+	// record the content negotiation decisions, for diagnostic logging
+	trace := &acceptable.Trace{}
+	req = req.WithContext(acceptable.WithTrace(req.Context(), trace))
+	defer slog.Debug("content negotiation", "trace", trace)
+
 	c := acceptable.RespondWith{Template: template}
 	err := c.RenderBestMatch(rw, req,
 		offer.JSON("  ").
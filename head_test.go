@@ -0,0 +1,165 @@
+package acceptable_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/rickb777/acceptable"
+	"github.com/rickb777/acceptable/data"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+func countingSupplier(calls *int, v any) data.Supplier {
+	return func(data.Chosen) (any, error) {
+		*calls++
+		return v, nil
+	}
+}
+
+func Test_head_should_not_obtain_content(t *testing.T) {
+	// Given ...
+	calls := 0
+	a := offer.JSON().With(data.Lazy(countingSupplier(&calls, point{X: 1, Y: 2})).ETag("abc"), "*")
+
+	req, _ := http.NewRequest("HEAD", "/", nil)
+	req.Header.Add(AcceptEncoding, "gzip")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(calls).ToBe(t, 0)
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Header().Get(ContentType)).ToBe(t, "application/json")
+	expect.String(w.Header().Get(ContentEncoding)).ToBe(t, "gzip")
+	expect.String(w.Header().Get(ETag)).ToBe(t, `"abc"`)
+	expect.String(w.Header().Get(ContentLength)).ToBe(t, "")
+	expect.Number(w.Body.Len()).ToBe(t, 0)
+}
+
+func Test_head_should_report_known_content_length(t *testing.T) {
+	// Given ...
+	calls := 0
+	a := offer.ImagePNG().With(data.Lazy(countingSupplier(&calls, []byte("12345"))).ContentLength(5), "*")
+
+	// the length is only relied on for HEAD
+	for method, length := range map[string]string{"HEAD": "5", "GET": ""} {
+		req, _ := http.NewRequest(method, "/", nil)
+		w := httptest.NewRecorder()
+
+		// When ...
+		err := acceptable.RenderBestMatch(w, req, a)
+
+		// Then ...
+		expect.Error(err).Not().ToHaveOccurred(t)
+		expect.Number(w.Code).I(method).ToBe(t, 200)
+		expect.String(w.Header().Get(ContentLength)).I(method).ToBe(t, length)
+	}
+
+	expect.Number(calls).ToBe(t, 1) // for GET only
+}
+
+func Test_head_should_omit_content_length_when_compressed(t *testing.T) {
+	// Given ...
+	a := offer.TextPlain().With(data.Of("1234").ContentLength(5), "*")
+
+	req, _ := http.NewRequest("HEAD", "/", nil)
+	req.Header.Add(AcceptEncoding, "gzip")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(w.Header().Get(ContentEncoding)).ToBe(t, "gzip")
+	expect.String(w.Header().Get(ContentLength)).ToBe(t, "")
+}
+
+func Test_head_should_have_the_same_content_encoding_as_get_when_the_processor_compresses(t *testing.T) {
+	// Given ...
+	a := offer.Of(offer.TXTProcessor(offer.MidCompression), "text/plain").With("Hello world", "*")
+
+	get, _ := http.NewRequest("GET", "/", nil)
+	get.Header.Add(AcceptEncoding, "gzip")
+	gw := httptest.NewRecorder()
+
+	head, _ := http.NewRequest("HEAD", "/", nil)
+	head.Header.Add(AcceptEncoding, "gzip")
+	hw := httptest.NewRecorder()
+
+	// When ...
+	err1 := acceptable.RenderBestMatch(gw, get, a)
+	err2 := acceptable.RenderBestMatch(hw, head, a)
+
+	// Then ...
+	expect.Error(err1).Not().ToHaveOccurred(t)
+	expect.Error(err2).Not().ToHaveOccurred(t)
+	expect.String(gw.Header().Get(ContentEncoding)).ToBe(t, "gzip")
+	expect.String(hw.Header().Get(ContentEncoding)).ToBe(t, "gzip")
+	expect.String(hw.Header().Get(Vary)).ToBe(t, gw.Header().Get(Vary))
+	expect.String(hw.Header().Get(ContentLength)).ToBe(t, strconv.Itoa(gw.Body.Len()))
+	expect.Number(hw.Body.Len()).ToBe(t, 0)
+}
+
+func Test_head_should_not_obtain_content_for_a_custom_processor_without_accept_encoding(t *testing.T) {
+	// Given ...
+	calls := 0
+	a := offer.Of(offer.TXTProcessor(offer.MidCompression), "text/plain").With(data.Lazy(countingSupplier(&calls, "Hello world")), "*")
+
+	req, _ := http.NewRequest("HEAD", "/", nil)
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(calls).ToBe(t, 0)
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Header().Get(ContentEncoding)).ToBe(t, "")
+}
+
+func Test_head_should_give_304_without_obtaining_content(t *testing.T) {
+	// Given ...
+	calls := 0
+	a := offer.JSON().With(data.Lazy(countingSupplier(&calls, point{X: 1, Y: 2})).ETag("abc"), "*")
+
+	req, _ := http.NewRequest("HEAD", "/", nil)
+	req.Header.Add(IfNoneMatch, `"abc"`)
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(calls).ToBe(t, 0)
+	expect.Number(w.Code).ToBe(t, 304)
+}
+
+func Test_head_should_obtain_content_for_auto_etag(t *testing.T) {
+	// Given ...
+	calls := 0
+	a := offer.JSON().With(data.Lazy(countingSupplier(&calls, point{X: 1, Y: 2})), "*")
+
+	req, _ := http.NewRequest("HEAD", "/", nil)
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RespondWith{AutoETag: true}.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(calls).ToBe(t, 1)
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Header().Get(ETag)).Not().ToBe(t, "")
+	expect.String(w.Header().Get(ContentLength)).ToBe(t, "14")
+	expect.Number(w.Body.Len()).ToBe(t, 0)
+}
@@ -0,0 +1,203 @@
+package header
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rickb777/acceptable/headername"
+)
+
+// CacheControl holds the directives of a Cache-Control header (RFC-9111 section 5.2). It is
+// used both for parsing request headers (see ParseCacheControl) and for building response
+// headers (see CacheControl.String).
+//
+// Directives that take a delta-seconds value are pointers; nil means the directive is absent.
+// Use DeltaSeconds to set them, e.g.
+//
+//	cc := header.CacheControl{Public: true, MaxAge: header.DeltaSeconds(time.Hour), Immutable: true}
+type CacheControl struct {
+	// directives used in both requests and responses
+	MaxAge      *time.Duration // max-age
+	NoCache     bool           // no-cache
+	NoStore     bool           // no-store
+	NoTransform bool           // no-transform
+
+	// response directives
+	Public               bool           // public
+	Private              bool           // private
+	MustRevalidate       bool           // must-revalidate
+	ProxyRevalidate      bool           // proxy-revalidate
+	MustUnderstand       bool           // must-understand
+	Immutable            bool           // immutable (RFC-8246)
+	SMaxAge              *time.Duration // s-maxage
+	StaleWhileRevalidate *time.Duration // stale-while-revalidate (RFC-5861)
+	StaleIfError         *time.Duration // stale-if-error (RFC-5861)
+
+	// request directives
+	MaxStale     *time.Duration // max-stale; negative when no value was given (i.e. any staleness)
+	MinFresh     *time.Duration // min-fresh
+	OnlyIfCached bool           // only-if-cached
+
+	// Extensions holds any other directives, unaltered.
+	Extensions []string
+}
+
+// DeltaSeconds is a convenience function for setting directives such as CacheControl.MaxAge.
+func DeltaSeconds(d time.Duration) *time.Duration {
+	return &d
+}
+
+// ParseCacheControl parses a Cache-Control header value. Directive names are case-insensitive.
+// Field names listed with no-cache or private are not retained.
+func ParseCacheControl(value string) CacheControl {
+	var cc CacheControl
+
+	for _, directive := range splitDirectives(value) {
+		name, arg, hasArg := strings.Cut(directive, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		arg = strings.Trim(strings.TrimSpace(arg), `"`)
+
+		switch name {
+		case "":
+			// ignored
+		case "max-age":
+			cc.MaxAge = parseDeltaSeconds(arg)
+		case "no-cache":
+			cc.NoCache = true
+		case "no-store":
+			cc.NoStore = true
+		case "no-transform":
+			cc.NoTransform = true
+		case "public":
+			cc.Public = true
+		case "private":
+			cc.Private = true
+		case "must-revalidate":
+			cc.MustRevalidate = true
+		case "proxy-revalidate":
+			cc.ProxyRevalidate = true
+		case "must-understand":
+			cc.MustUnderstand = true
+		case "immutable":
+			cc.Immutable = true
+		case "s-maxage":
+			cc.SMaxAge = parseDeltaSeconds(arg)
+		case "stale-while-revalidate":
+			cc.StaleWhileRevalidate = parseDeltaSeconds(arg)
+		case "stale-if-error":
+			cc.StaleIfError = parseDeltaSeconds(arg)
+		case "max-stale":
+			if hasArg {
+				cc.MaxStale = parseDeltaSeconds(arg)
+			} else {
+				cc.MaxStale = DeltaSeconds(-1)
+			}
+		case "min-fresh":
+			cc.MinFresh = parseDeltaSeconds(arg)
+		case "only-if-cached":
+			cc.OnlyIfCached = true
+		default:
+			cc.Extensions = append(cc.Extensions, strings.TrimSpace(directive))
+		}
+	}
+
+	return cc
+}
+
+// RequestCacheControl gets the Cache-Control directives of a request. For HTTP/1.0
+// compatibility, "Pragma: no-cache" is treated as "Cache-Control: no-cache" when there
+// is no Cache-Control header (RFC-9111 section 5.4).
+func RequestCacheControl(req *http.Request) CacheControl {
+	values, present := req.Header[headername.CacheControl]
+	if !present {
+		if strings.Contains(strings.ToLower(req.Header.Get(headername.Pragma)), "no-cache") {
+			return CacheControl{NoCache: true}
+		}
+		return CacheControl{}
+	}
+	return ParseCacheControl(strings.Join(values, ","))
+}
+
+// ForcesRevalidation is true when a request's directives require any stored response to be
+// validated with the origin server before it is used, i.e. no-cache or max-age=0.
+func (cc CacheControl) ForcesRevalidation() bool {
+	return cc.NoCache || (cc.MaxAge != nil && *cc.MaxAge <= 0)
+}
+
+// String formats the directives as a Cache-Control header value.
+func (cc CacheControl) String() string {
+	var parts []string
+	flag := func(set bool, name string) {
+		if set {
+			parts = append(parts, name)
+		}
+	}
+	delta := func(d *time.Duration, name string) {
+		if d != nil {
+			parts = append(parts, fmt.Sprintf("%s=%d", name, *d/time.Second))
+		}
+	}
+
+	flag(cc.Public, "public")
+	flag(cc.Private, "private")
+	flag(cc.NoCache, "no-cache")
+	flag(cc.NoStore, "no-store")
+	flag(cc.NoTransform, "no-transform")
+	flag(cc.MustRevalidate, "must-revalidate")
+	flag(cc.ProxyRevalidate, "proxy-revalidate")
+	flag(cc.MustUnderstand, "must-understand")
+	delta(cc.MaxAge, "max-age")
+	delta(cc.SMaxAge, "s-maxage")
+	delta(cc.StaleWhileRevalidate, "stale-while-revalidate")
+	delta(cc.StaleIfError, "stale-if-error")
+	flag(cc.Immutable, "immutable")
+
+	if cc.MaxStale != nil && *cc.MaxStale < 0 {
+		parts = append(parts, "max-stale")
+	} else {
+		delta(cc.MaxStale, "max-stale")
+	}
+	delta(cc.MinFresh, "min-fresh")
+	flag(cc.OnlyIfCached, "only-if-cached")
+
+	parts = append(parts, cc.Extensions...)
+	return strings.Join(parts, ", ")
+}
+
+// splitDirectives splits a comma-separated list, allowing for commas within quoted strings.
+func splitDirectives(value string) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				parts = append(parts, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, value[start:])
+}
+
+const maxDeltaSeconds = 1 << 31
+
+// parseDeltaSeconds parses a delta-seconds value; invalid values are treated as zero, which
+// is the safe interpretation (RFC-9111 section 1.2.2).
+func parseDeltaSeconds(s string) *time.Duration {
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); !ok || ne.Err != strconv.ErrRange {
+			return DeltaSeconds(0)
+		}
+	}
+	if n > maxDeltaSeconds {
+		n = maxDeltaSeconds // see RFC-9111 section 1.2.2
+	}
+	return DeltaSeconds(time.Duration(n) * time.Second)
+}
@@ -0,0 +1,89 @@
+package header
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rickb777/expect"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected CacheControl
+		str      string
+	}{
+		{
+			input:    "",
+			expected: CacheControl{},
+			str:      "",
+		},
+		{
+			input:    "max-age=0",
+			expected: CacheControl{MaxAge: DeltaSeconds(0)},
+			str:      "max-age=0",
+		},
+		{
+			input:    "No-Cache, no-store",
+			expected: CacheControl{NoCache: true, NoStore: true},
+			str:      "no-cache, no-store",
+		},
+		{
+			input:    "public, max-age=3600, s-maxage=60, immutable",
+			expected: CacheControl{Public: true, MaxAge: DeltaSeconds(time.Hour), SMaxAge: DeltaSeconds(time.Minute), Immutable: true},
+			str:      "public, max-age=3600, s-maxage=60, immutable",
+		},
+		{
+			input:    `private="Set-Cookie, X-Foo", must-revalidate, stale-while-revalidate=30, stale-if-error=86400`,
+			expected: CacheControl{Private: true, MustRevalidate: true, StaleWhileRevalidate: DeltaSeconds(30 * time.Second), StaleIfError: DeltaSeconds(24 * time.Hour)},
+			str:      "private, must-revalidate, stale-while-revalidate=30, stale-if-error=86400",
+		},
+		{
+			input:    "max-stale, min-fresh=10, only-if-cached, no-transform",
+			expected: CacheControl{MaxStale: DeltaSeconds(-1), MinFresh: DeltaSeconds(10 * time.Second), OnlyIfCached: true, NoTransform: true},
+			str:      "no-transform, max-stale, min-fresh=10, only-if-cached",
+		},
+		{
+			input:    `max-stale=5, foo="bar", max-age=abc`,
+			expected: CacheControl{MaxStale: DeltaSeconds(5 * time.Second), MaxAge: DeltaSeconds(0), Extensions: []string{`foo="bar"`}},
+			str:      `max-age=0, max-stale=5, foo="bar"`,
+		},
+		{
+			input:    "max-age=99999999999999999999",
+			expected: CacheControl{MaxAge: DeltaSeconds(maxDeltaSeconds * time.Second)},
+			str:      "max-age=2147483648",
+		},
+	}
+
+	for i, c := range cases {
+		actual := ParseCacheControl(c.input)
+		expect.Any(actual).I(i).ToBe(t, c.expected)
+		expect.String(actual.String()).I(i).ToBe(t, c.str)
+	}
+}
+
+func TestRequestCacheControl(t *testing.T) {
+	cases := []struct {
+		hdrs       map[string]string
+		revalidate bool
+	}{
+		{hdrs: map[string]string{}, revalidate: false},
+		{hdrs: map[string]string{"Cache-Control": "max-age=10"}, revalidate: false},
+		{hdrs: map[string]string{"Cache-Control": "max-age=0"}, revalidate: true},
+		{hdrs: map[string]string{"Cache-Control": "no-cache"}, revalidate: true},
+		{hdrs: map[string]string{"Pragma": "no-cache"}, revalidate: true},
+		{hdrs: map[string]string{"Pragma": "no-cache", "Cache-Control": "max-age=10"}, revalidate: false},
+	}
+
+	for i, c := range cases {
+		req, _ := http.NewRequest("GET", "/", nil)
+		for h, v := range c.hdrs {
+			req.Header.Set(h, v)
+		}
+
+		cc := RequestCacheControl(req)
+
+		expect.Bool(cc.ForcesRevalidation()).I(i).ToBe(t, c.revalidate)
+	}
+}
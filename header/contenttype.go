@@ -4,6 +4,7 @@ import (
 	"io"
 	"mime"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/rickb777/acceptable/headername"
@@ -43,6 +44,17 @@ func (ct ContentType) Subtype() string {
 	return s
 }
 
+// Param gets the value of a named parameter. The boolean result is false if there is no
+// such parameter.
+func (ct ContentType) Param(key string) (string, bool) {
+	for _, p := range ct.Params {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
 // AsMediaRange converts this ContentType to a MediaRange.
 // The default quality should be 1.
 func (ct ContentType) AsMediaRange(quality float64) MediaRange {
@@ -135,6 +147,8 @@ func ParseContentType(ct string) ContentType {
 		for k, v := range params {
 			paramsKV = append(paramsKV, KV{Key: k, Value: v})
 		}
+		// map iteration order is random so sort the parameters to ensure a consistent result
+		sort.Slice(paramsKV, func(i, j int) bool { return paramsKV[i].Key < paramsKV[j].Key })
 	}
 	return ContentType{MediaType: mt, Params: paramsKV}
 }
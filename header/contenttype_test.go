@@ -48,6 +48,23 @@ func TestParseContentType(t *testing.T) {
 	})
 }
 
+func TestParseContentType_sorts_params(t *testing.T) {
+	ct := ParseContentType("text/html; level=1; charset=utf-8; format=flowed")
+
+	expect.Slice(ct.Params).ToBe(t,
+		KV{Key: "charset", Value: "utf-8"},
+		KV{Key: "format", Value: "flowed"},
+		KV{Key: "level", Value: "1"},
+	)
+
+	v, ok := ct.Param("level")
+	expect.String(v).ToBe(t, "1")
+	expect.Bool(ok).ToBeTrue(t)
+
+	_, ok = ct.Param("version")
+	expect.Bool(ok).ToBeFalse(t)
+}
+
 func TestContentType_IsTextual(t *testing.T) {
 	cases := []ContentType{
 		{MediaType: "text/plain"},
@@ -7,6 +7,9 @@
 //
 // For "If-None-Match" use the ETagsOf function (also useful for "If-Match").
 //
+// For "Cache-Control" use the ParseCacheControl function, or RequestCacheControl for requests. The CacheControl
+// type also builds response headers.
+//
 // # Accept
 //
 // The Accept header is parsed using ParseMediaRanges(hdr), which returns the slice of media ranges, e.g.
@@ -4,8 +4,10 @@ package headername
 const (
 	Accept              = "Accept"
 	AcceptCharset       = "Accept-Charset"
-	AcceptEncoding      = "Accept-Encoding" // AcceptEncoding is handled effectively by net/http and can be disregarded here
+	AcceptEncoding      = "Accept-Encoding"
 	AcceptLanguage      = "Accept-Language"
+	AcceptRanges        = "Accept-Ranges"
+	Age                 = "Age"
 	Allow               = "Allow"
 	Authorization       = "Authorization"
 	CacheControl        = "Cache-Control"
@@ -13,17 +15,24 @@ const (
 	ContentEncoding     = "Content-Encoding"
 	ContentLanguage     = "Content-Language"
 	ContentLength       = "Content-Length"
+	ContentLocation     = "Content-Location"
+	ContentRange        = "Content-Range"
 	ContentType         = "Content-Type"
 	Cookie              = "Cookie" // Cookie and Set-Cookie are handled effectively by the standard library APIs
+	Date                = "Date"
 	ETag                = "ETag"
 	Expires             = "Expires"
 	IfModifiedSince     = "If-Modified-Since"
 	IfMatch             = "If-Match"
 	IfNoneMatch         = "If-None-Match"
+	IfRange             = "If-Range"
+	IfUnmodifiedSince   = "If-Unmodified-Since"
+	LastEventID         = "Last-Event-ID"
 	LastModified        = "Last-Modified"
 	Location            = "Location"
 	Origin              = "Origin"
 	Pragma              = "Pragma"
+	Range               = "Range"
 	Server              = "Server"
 	SetCookie           = "Set-Cookie"
 	Upgrade             = "Upgrade"
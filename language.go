@@ -0,0 +1,136 @@
+package acceptable
+
+import (
+	"sort"
+
+	"github.com/rickb777/acceptable/header"
+	offerpkg "github.com/rickb777/acceptable/offer"
+	"golang.org/x/text/language"
+)
+
+// LanguageMatching selects the algorithm used to match the Accept-Language header against
+// the languages provided by each offer.
+type LanguageMatching int
+
+const (
+	// BasicFiltering implements the basic filtering algorithm defined in RFC-4647 section 3.3.1.
+	// Language tags are compared as strings, so "en" matches "en" and "en-GB" but "en-GB" does
+	// not match "en-US", nor does "zh-TW" match "zh-Hant". This is the default.
+	BasicFiltering LanguageMatching = iota
+
+	// BestFit uses a language.Matcher from golang.org/x/text/language, built from the offer's
+	// languages. This takes account of scripts, regions and linguistic distance, so that for
+	// example "zh-TW" matches "zh-Hant" and "es-419" matches "es-MX". The chosen language is
+	// the canonical form of the offered tag and the confidence of the match is recorded in
+	// offer.Match.
+	BestFit
+)
+
+// LanguageMatcher is the default for Negotiator.LanguageMatcher, which selects the language
+// matching algorithm. It is only read by DefaultNegotiator.
+var LanguageMatcher = BasicFiltering
+
+// languageChoice is one language provided by an offer that matches the accepted languages.
+type languageChoice struct {
+	lookup     string // the language used to look up the offer's data
+	language   string // the chosen language
+	confidence language.Confidence
+}
+
+// languageMatch finds the languages provided by an offer, given the accepted languages.
+// The result is in order of preference, best first.
+type languageMatch func(languages header.PrecedenceValues, offer offerpkg.Offer) []languageChoice
+
+func basicMatch(langMatch func(acceptedLang, offeredLang string) bool) languageMatch {
+	return func(languages header.PrecedenceValues, offer offerpkg.Offer) []languageChoice {
+		var choices []languageChoice
+		for _, prefLang := range languages {
+			for _, offeredLang := range offer.Langs {
+				if langMatch(prefLang.Value, offeredLang) && prefLang.Quality > 0 {
+					if offeredLang == "*" && prefLang.Value != "*" {
+						offeredLang = prefLang.Value
+					}
+					choices = addChoice(choices, languageChoice{lookup: offeredLang, language: offeredLang, confidence: language.No})
+				}
+			}
+		}
+		return choices
+	}
+}
+
+func bestFitMatch(languages header.PrecedenceValues, offer offerpkg.Offer) []languageChoice {
+	var accepted []language.Tag
+	anyAccepted := false
+	for _, prefLang := range languages {
+		if prefLang.Quality <= 0 {
+			continue
+		}
+		if prefLang.Value == "*" {
+			anyAccepted = true
+		} else if tag, err := language.Parse(prefLang.Value); err == nil {
+			accepted = append(accepted, tag)
+		}
+	}
+
+	var supported []language.Tag
+	var keys []string
+	anyOffered := false
+	for _, offeredLang := range offer.Langs {
+		if offeredLang == "*" {
+			anyOffered = true
+		} else if tag, err := language.Parse(offeredLang); err == nil {
+			supported = append(supported, tag)
+			keys = append(keys, offeredLang)
+		}
+	}
+
+	var choices []languageChoice
+
+	if len(supported) > 0 && len(accepted) > 0 {
+		_, i, confidence := language.NewMatcher(supported).Match(accepted...)
+		if confidence > language.No {
+			choices = append(choices, languageChoice{lookup: keys[i], language: supported[i].String(), confidence: confidence})
+
+			// the other offered languages are ranked by how well each one matches on its own
+			var others []languageChoice
+			for j, tag := range supported {
+				if j != i {
+					_, _, conf := language.NewMatcher([]language.Tag{tag}).Match(accepted...)
+					if conf > language.No {
+						others = addChoice(others, languageChoice{lookup: keys[j], language: tag.String(), confidence: conf})
+					}
+				}
+			}
+			sort.SliceStable(others, func(x, y int) bool { return others[x].confidence > others[y].confidence })
+			for _, o := range others {
+				choices = addChoice(choices, o)
+			}
+		}
+	}
+
+	if anyOffered {
+		if len(accepted) > 0 {
+			lang := accepted[0].String()
+			return addChoice(choices, languageChoice{lookup: lang, language: lang, confidence: language.Exact})
+		}
+		if anyAccepted {
+			return addChoice(choices, languageChoice{lookup: "*", language: "*", confidence: language.Exact})
+		}
+	}
+
+	if len(choices) == 0 && anyAccepted && len(supported) > 0 {
+		return []languageChoice{{lookup: keys[0], language: supported[0].String(), confidence: language.Exact}}
+	}
+
+	return choices
+}
+
+// addChoice appends a choice unless its lookup language has already been chosen.
+func addChoice(choices []languageChoice, choice languageChoice) []languageChoice {
+	for _, c := range choices {
+		if c.lookup == choice.lookup {
+			return choices
+		}
+	}
+	return append(choices, choice)
+}
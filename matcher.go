@@ -3,11 +3,14 @@ package acceptable
 import (
 	"fmt"
 	"net/http"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/rickb777/acceptable/header"
 	"github.com/rickb777/acceptable/headername"
 	offerpkg "github.com/rickb777/acceptable/offer"
+	"golang.org/x/text/language"
 )
 
 // IsAjax tests whether a request has the Ajax header sent by browsers for XHR requests.
@@ -26,9 +29,11 @@ func IsAjax(req *http.Request) bool {
 // of providing an Ajax response are considered by the content negotiation algorithm.
 // The other offers are discarded.
 //
-// The order of offers is important. It determines the order they are compared against
-// the request headers, and this determines what defaults will be used when exact matching
-// is not possible.
+// Offers are ranked by the quality of the matching media range in the Accept header multiplied
+// by the offer's source quality (see offer.Offer.SourceQuality). The order of offers is also
+// important. It determines the order they are compared against the request headers, which
+// breaks ties between offers of equal rank, and this determines what defaults will be used
+// when exact matching is not possible.
 //
 // If no available offers are provided, the response will normally be nil. Note too that
 // Ajax requests will result in nil being returned if no offer is capable of handling
@@ -38,52 +43,95 @@ func IsAjax(req *http.Request) bool {
 // of the offers has its Handle406 set non-zero. This fallback match allows custom error
 // messages to be returned according to the context. The
 func BestRequestMatch(req *http.Request, available ...offerpkg.Offer) *offerpkg.Match {
-	accept, accLang, vary := readHeaders(req)
+	return DefaultNegotiator().BestRequestMatch(req, available...)
+}
+
+// BestRequestMatch is as per the BestRequestMatch function, using this negotiator's settings.
+func (n *Negotiator) BestRequestMatch(req *http.Request, available ...offerpkg.Offer) *offerpkg.Match {
+	c, mrs, languages, availables, vary := n.prepare(req, available)
+
+	ranked := c.rankedMatches(mrs, languages, availables, vary)
+
+	if len(ranked) > 0 {
+		best := ranked[0]
+		c.recordChosen(best)
+		return best
+	}
+
+	return c.searchForFallbackOffer(availables.CanHandle406(), mrs)
+}
+
+// RankedRequestMatches finds all the acceptable combinations of the available offers and
+// their languages, ranked with the best match first. Each match holds its effective quality.
+// The first result is the same as would be returned by BestRequestMatch.
+//
+// This is useful, for example, to list alternative representations in Link headers, to
+// fall back to the next representation when a processor fails, or to present a choice to
+// the user.
+//
+// Whenever the result is empty, the response should be 406-Not Acceptable. Unlike
+// BestRequestMatch, no fallback match is returned for offers that have Handle406As set.
+func RankedRequestMatches(req *http.Request, available ...offerpkg.Offer) []*offerpkg.Match {
+	return DefaultNegotiator().RankedRequestMatches(req, available...)
+}
+
+// RankedRequestMatches is as per the RankedRequestMatches function, using this negotiator's settings.
+func (n *Negotiator) RankedRequestMatches(req *http.Request, available ...offerpkg.Offer) []*offerpkg.Match {
+	c, mrs, languages, availables, vary := n.prepare(req, available)
+
+	ranked := c.rankedMatches(mrs, languages, availables, vary)
+
+	if len(ranked) > 0 {
+		c.recordChosen(ranked[0])
+	}
 
-	availables := offerpkg.Offers(available)
+	return ranked
+}
+
+func (n *Negotiator) prepare(req *http.Request, available offerpkg.Offers) (negotiation, header.MediaRanges, header.PrecedenceValues, offerpkg.Offers, []string) {
+	accept, accLang, vary := readHeaders(req)
 
 	mrs := header.ParseMediaRanges(accept).WithDefault()
 	languages := header.ParsePrecedenceValues(accLang).WithDefault()
 
 	if IsAjax(req) {
-		availables = availables.Filter("application", "json")
+		available = available.Filter("application", "json")
 	}
 
-	c := context(fmt.Sprintf("%s %s", req.Method, req.URL))
-	best := c.bestMatch(mrs, languages, availables, vary)
-
-	if best != nil {
-		charsets := header.ParsePrecedenceValues(req.Header.Get(headername.AcceptCharset))
-		best.Charset = "utf-8"
-		// If at all possible, stick with utf-8 because (a) it is recommended; (b) no transcoding is necessary.
-		// If other charsets are listed, choose one only if utf-8 is not included.
-		if len(charsets) > 0 && !(charsets.Contains("utf-8") || charsets.Contains("utf8")) {
-			// something other than utf-8 is legacy and deprecated, but supported anyway
-			best.Charset = charsets[0].Value
-			best.Vary = append(best.Vary, headername.AcceptCharset)
-		}
-		return best
+	c := negotiation{
+		req:             req,
+		name:            fmt.Sprintf("%s %s", req.Method, req.URL),
+		trace:           TraceFrom(req.Context()),
+		debug:           n.Debug,
+		languageMatcher: n.LanguageMatcher,
 	}
-
-	return c.searchForFallbackOffer(availables.CanHandle406(), mrs)
+	if c.trace != nil {
+		c.trace.Request = c.name
+	}
+	return c, mrs, languages, available, vary
 }
 
-func (c context) searchForFallbackOffer(available offerpkg.Offers, mrs header.MediaRanges) *offerpkg.Match {
+func (c negotiation) searchForFallbackOffer(available offerpkg.Offers, mrs header.MediaRanges) *offerpkg.Match {
 	availableFor406 := available.CanHandle406()
 	if len(availableFor406) == 1 {
-		return availableFor406[0].BuildFallbackMatch()
+		return c.fallback(availableFor406[0], "the only 406 handler")
 	} else if len(availableFor406) > 1 {
 		// matching an excluded media range is the worst case so we try to avoid this
 		remainingFor406 := c.removeExcludedOffers(mrs, availableFor406)
 		if len(remainingFor406) > 0 {
-			return remainingFor406[0].BuildFallbackMatch()
+			return c.fallback(remainingFor406[0], "the first 406 handler not excluded")
 		}
 		// nope, go ahead anyway
-		return availableFor406[0].BuildFallbackMatch()
+		return c.fallback(availableFor406[0], "the first 406 handler, although excluded")
 	}
 	return nil
 }
 
+func (c negotiation) fallback(offer offerpkg.Offer, reason string) *offerpkg.Match {
+	c.record(TraceStep{Kind: TraceFallback406, Offer: offer.String(), Reason: reason})
+	return offer.BuildFallbackMatch()
+}
+
 func readHeaders(req *http.Request) (accept, accLang string, vary []string) {
 	accept = req.Header.Get(headername.Accept)
 	accLang = req.Header.Get(headername.AcceptLanguage)
@@ -93,42 +141,129 @@ func readHeaders(req *http.Request) (accept, accLang string, vary []string) {
 	if accLang != "" {
 		vary = append(vary, headername.AcceptLanguage)
 	}
+	if _, present := req.Header[headername.AcceptEncoding]; present {
+		vary = append(vary, headername.AcceptEncoding)
+	}
+	if _, present := req.Header[headername.AcceptCharset]; present {
+		vary = append(vary, headername.AcceptCharset)
+	}
 	return accept, accLang, vary
 }
 
-// used for diagnostics
-type context string
+// negotiation holds the state for a single request.
+type negotiation struct {
+	req             *http.Request
+	name            string
+	trace           *Trace
+	debug           func(string, ...any)
+	languageMatcher LanguageMatching
+}
 
-// bestMatch finds the content type and language that best matches the accepted media
-// ranges and languages.
-// The result contains the best match, based on the rules of RFC-7231.
+// recordChosen records the content coding and charset of the chosen match.
+func (c negotiation) recordChosen(best *offerpkg.Match) {
+	if _, present := c.req.Header[headername.AcceptEncoding]; present {
+		c.record(TraceStep{Kind: TraceContentCoding, ContentCoding: best.ContentEncoding})
+	}
+	c.record(TraceStep{Kind: TraceCharset, Charset: best.Charset})
+}
+
+// removeUnsupportableOffers removes the offers that cannot provide an acceptable content coding
+// or charset.
+func (c negotiation) removeUnsupportableOffers(available offerpkg.Offers) offerpkg.Offers {
+	remaining := make(offerpkg.Offers, 0, len(available))
+	for _, offer := range available {
+		if _, ok := offer.NegotiateContentCoding(c.req); !ok {
+			c.record(TraceStep{Kind: TraceExcluded, Offer: offer.String(), Reason: "no acceptable content coding"})
+		} else if _, ok := offer.NegotiateCharset(c.req); !ok {
+			c.record(TraceStep{Kind: TraceExcluded, Offer: offer.String(), Reason: "no acceptable charset"})
+		} else {
+			remaining = append(remaining, offer)
+		}
+	}
+	return remaining
+}
+
+// record notes a decision in the trace, if there is one.
+func (c negotiation) record(step TraceStep) {
+	if c.debug != nil {
+		c.debug("%s %s\n", c.name, step)
+	}
+	c.trace.add(step)
+}
+
+// candidate is a potential match, along with the information needed to rank it.
+type candidate struct {
+	match       *offerpkg.Match
+	offer       int // index of the offer
+	lookup      string
+	specificity int
+}
+
+// better returns true if a candidate should be ranked higher than another.
+func (a candidate) better(b candidate) bool {
+	return a.match.Quality > b.match.Quality ||
+		(a.match.Quality == b.match.Quality && a.specificity > b.specificity)
+}
+
+// rankedMatches finds the content types and languages that match the accepted media
+// ranges and languages, with the best match first.
+// The results are based on the rules of RFC-7231.
 //
-// On exit, the result will contain the preferred language, if this is known.
+// Each result will contain the matched language, if this is known.
 //
-// Whenever the result is nil, the response should be 406-Not Acceptable.
-// If no available offers are provided, the response will always be nil.
-func (c context) bestMatch(mrs header.MediaRanges, languages header.PrecedenceValues, availables offerpkg.Offers, vary []string) (best *offerpkg.Match) {
+// Whenever the result is empty, the response should be 406-Not Acceptable.
+// If no available offers are provided, the response will always be empty.
+func (c negotiation) rankedMatches(mrs header.MediaRanges, languages header.PrecedenceValues, availables offerpkg.Offers, vary []string) []*offerpkg.Match {
 	// first pass - remove offers that match exclusions
 	// (this doesn't apply to language exclusions because we always allow at least one language match)
-	remaining := c.removeExcludedOffers(mrs, availables)
+	remaining := c.removeUnsupportableOffers(c.removeExcludedOffers(mrs, availables))
 
-	foundCtMatch := false
+	exactLang, nearLang := basicMatch(equalOrPrefix), basicMatch(equalOrWildcard)
+	if c.languageMatcher == BestFit {
+		exactLang, nearLang = bestFitMatch, bestFitMatch
+	}
+
+	passes := []struct {
+		kind             string
+		contentTypeMatch func(header.MediaRange, offerpkg.Offer) bool
+		langMatch        languageMatch
+	}{
+		// second pass - find the exact-match media-range and language combinations
+		{kind: "exact", contentTypeMatch: exactMatch, langMatch: exactLang},
+		// third pass - find the near-match media-range and language combinations
+		{kind: "near", contentTypeMatch: nearMatch, langMatch: nearLang},
+	}
 
 	for i := 1; i <= 2; i++ {
-		// second pass - find the first exact-match media-range and language combination
-		for _, offer := range remaining {
-			best, foundCtMatch = c.findBestMatch(mrs, languages, offer, vary, exactMatch, equalOrPrefix, "exact")
-			if best != nil {
-				return best
+		foundCtMatch := false
+
+		var candidates []candidate
+		for _, pass := range passes {
+			for j, offer := range remaining {
+				found, ctMatch := c.findMatches(mrs, languages, offer, vary, pass.contentTypeMatch, pass.langMatch, pass.kind)
+				foundCtMatch = foundCtMatch || ctMatch
+				for _, f := range found {
+					f.offer = j
+					candidates = addCandidate(candidates, f)
+				}
 			}
 		}
 
-		// third pass - find the first near-match media-range and language combination
-		for _, offer := range remaining {
-			best, foundCtMatch = c.findBestMatch(mrs, languages, offer, vary, nearMatch, equalOrWildcard, "near")
-			if best != nil {
-				return best
+		if len(candidates) > 0 {
+			// the candidates are ranked by their effective quality multiplied by their source quality,
+			// then by the specificity of the media range that determined the quality; the order
+			// of the passes, then the order of the offers and then the language preferences only
+			// break any remaining ties
+			sort.SliceStable(candidates, func(x, y int) bool { return candidates[x].better(candidates[y]) })
+
+			ranked := make([]*offerpkg.Match, len(candidates))
+			for k, cd := range candidates {
+				if i > 1 {
+					cd.match.Confidence = language.No
+				}
+				ranked[k] = cd.match
 			}
+			return ranked
 		}
 
 		if foundCtMatch {
@@ -137,22 +272,44 @@ func (c context) bestMatch(mrs header.MediaRanges, languages header.PrecedenceVa
 			// So go round another loop trying to match just the content type.
 			// Use a wildcard in place of the accepted language.
 			languages = header.WildcardPrecedenceValue
+			c.record(TraceStep{Kind: TraceLanguageFallback, Reason: "no acceptable language"})
 		} else {
 			break
 		}
 	}
 
-	Debug("%s is not acceptable for %d offers (%d available)\n", c, len(remaining), len(availables))
+	c.record(TraceStep{Kind: TraceNotAcceptable, Reason: fmt.Sprintf("%d offers (%d available)", len(remaining), len(availables))})
 	return nil // 406 - Not Acceptable
 }
 
-func (c context) removeExcludedOffers(mrs header.MediaRanges, available offerpkg.Offers) offerpkg.Offers {
+// addCandidate appends a candidate unless the same offer and language has already been found,
+// in which case the better of the two is kept.
+func addCandidate(candidates []candidate, cd candidate) []candidate {
+	for i, existing := range candidates {
+		if existing.offer == cd.offer && existing.lookup == cd.lookup {
+			if cd.better(existing) {
+				candidates[i] = cd
+			}
+			return candidates
+		}
+	}
+	return append(candidates, cd)
+}
+
+func (c negotiation) removeExcludedOffers(mrs header.MediaRanges, available offerpkg.Offers) offerpkg.Offers {
 	excluded := make([]bool, len(available))
 	for i, offer := range available {
-		for _, accepted := range mrs {
-			if accepted.Quality <= 0 &&
-				accepted.MediaType == offer.MediaType {
-				excluded[i] = true
+		if isConcrete(offer) {
+			// the most specific media range determines whether the offer is acceptable
+			mr, found := mostSpecificRange(mrs, offer)
+			excluded[i] = found && mr.Quality <= 0
+		} else {
+			for _, accepted := range mrs {
+				if accepted.Quality <= 0 &&
+					accepted.MediaType == offer.MediaType &&
+					paramsMatch(accepted, offer) {
+					excluded[i] = true
+				}
 			}
 		}
 	}
@@ -173,59 +330,130 @@ func (c context) removeExcludedOffers(mrs header.MediaRanges, available offerpkg
 		if !excluded[i] {
 			remaining = append(remaining, offer)
 		} else {
-			Debug("%s excluding offerpkg %s\n", c, offer)
+			c.record(TraceStep{Kind: TraceExcluded, Offer: offer.String(), Reason: "media range has q=0"})
 		}
 	}
 
 	return remaining
 }
 
-func (c context) findBestMatch(mrs header.MediaRanges, languages header.PrecedenceValues, offer offerpkg.Offer, vary []string,
+func (c negotiation) findMatches(mrs header.MediaRanges, languages header.PrecedenceValues, offer offerpkg.Offer, vary []string,
 	contentTypeMatch func(header.MediaRange, offerpkg.Offer) bool,
-	langMatch func(acceptedLang, offeredLang string) bool,
-	kind string) (*offerpkg.Match, bool) {
-
-	foundCtMatch := false
+	langMatch languageMatch,
+	kind string) ([]candidate, bool) {
 
 	for _, acceptedCT := range mrs {
-		if contentTypeMatch(acceptedCT, offer) {
-			foundCtMatch = true
-
-			for _, prefLang := range languages {
-				for _, offeredLang := range offer.Langs {
-					if langMatch(prefLang.Value, string(offeredLang)) {
-						Debug("%s try matching %s, lang=%s to %s, lang=%s\n", c, acceptedCT, prefLang, offer.ContentType, offeredLang)
-
-						if prefLang.Quality > 0 {
-							Debug("%s successfully matched %s, lang=%s to %s\n", c, acceptedCT, prefLang, offer)
-							if offeredLang == "*" && prefLang.Value != "*" {
-								offeredLang = prefLang.Value
-							}
-							m := offer.BuildMatch(acceptedCT.ContentType, offeredLang)
-							m.Vary = vary
-							return m, true
-						}
-					}
-				}
+		if acceptedCT.Quality > 0 && contentTypeMatch(acceptedCT, offer) {
+			choices := langMatch(languages, offer)
+			if len(choices) == 0 {
+				c.record(TraceStep{Kind: TraceNoLanguageMatch, Pass: kind, Offer: offer.String(), MediaRange: acceptedCT.String()})
+				return nil, true
+			}
+
+			quality, specificity := effectiveQuality(mrs, acceptedCT, offer)
+			quality *= sourceQuality(offer)
+
+			found := make([]candidate, len(choices))
+			for i, choice := range choices {
+				c.record(TraceStep{Kind: TraceMatched, Pass: kind, Offer: offer.String(), MediaRange: acceptedCT.String(), Language: choice.language, Quality: quality})
+				m := offer.BuildMatch(acceptedCT.ContentType, choice.lookup)
+				m.Language = choice.language
+				m.Confidence = choice.confidence
+				m.Quality = quality
+				m.Vary = slices.Clone(vary)
+				m.ContentEncoding, _ = offer.NegotiateContentCoding(c.req)
+				m.Charset, _ = offer.NegotiateCharset(c.req)
+				found[i] = candidate{match: m, lookup: choice.lookup, specificity: specificity}
 			}
+			return found, true
+		}
+	}
+
+	c.record(TraceStep{Kind: TraceNoMatch, Pass: kind, Offer: offer.String()})
+	return nil, false
+}
+
+// effectiveQuality gets the quality of an offer, along with the specificity of the media range
+// that determines it. For offers with a concrete media type, this is the quality of the most
+// specific matching media range (RFC-9110 section 12.5.1). Otherwise, it is simply the quality
+// of the accepted media range.
+func effectiveQuality(mrs header.MediaRanges, accepted header.MediaRange, offer offerpkg.Offer) (float64, int) {
+	if isConcrete(offer) {
+		if mr, found := mostSpecificRange(mrs, offer); found {
+			return mr.Quality, specificity(mr)
 		}
 	}
+	return accepted.Quality, specificity(accepted)
+}
+
+// mostSpecificRange finds the media range that most specifically matches an offer. For
+// example, "text/html;level=1" is more specific than "text/html", which is more specific
+// than "text/*", which is more specific than "*/*".
+func mostSpecificRange(mrs header.MediaRanges, offer offerpkg.Offer) (best header.MediaRange, found bool) {
+	for _, mr := range mrs {
+		if nearMatch(mr, offer) && (!found || specificity(mr) > specificity(best)) {
+			best, found = mr, true
+		}
+	}
+	return best, found
+}
+
+func specificity(mr header.MediaRange) int {
+	t, s := mr.Split()
+	if t == "*" {
+		return 0
+	} else if s == "*" {
+		return 1
+	}
+	return 2 + countParams(mr)
+}
 
-	Debug("%s no %s match for offerpkg %s\n", c, kind, offer)
-	return nil, foundCtMatch
+func isConcrete(offer offerpkg.Offer) bool {
+	t, s := offer.Split()
+	return t != "*" && s != "*"
 }
 
 //-------------------------------------------------------------------------------------------------
 
 func exactMatch(accepted header.MediaRange, offer offerpkg.Offer) bool {
-	return accepted.MediaType == offer.MediaType
+	return accepted.MediaType == offer.MediaType &&
+		paramsMatch(accepted, offer)
 }
 
 func nearMatch(accepted header.MediaRange, offer offerpkg.Offer) bool {
 	return equalOrWildcard(accepted.Type(), offer.Type()) &&
-		equalOrWildcard(accepted.Subtype(), offer.Subtype())
+		equalOrWildcard(accepted.Subtype(), offer.Subtype()) &&
+		paramsMatch(accepted, offer)
+}
+
+// paramsMatch returns true if every parameter of the accepted media range is also
+// provided by the offer with the same value. The charset parameter is ignored here
+// because it is handled by the Accept-Charset negotiation.
+func paramsMatch(accepted header.MediaRange, offer offerpkg.Offer) bool {
+	for _, p := range accepted.Params {
+		if p.Key == charset {
+			continue
+		}
+		v, exists := offer.Param(p.Key)
+		if !exists || !strings.EqualFold(v, strings.Trim(p.Value, `"`)) {
+			return false
+		}
+	}
+	return true
+}
+
+func countParams(accepted header.MediaRange) int {
+	n := 0
+	for _, p := range accepted.Params {
+		if p.Key != charset {
+			n++
+		}
+	}
+	return n
 }
 
+const charset = "charset"
+
 func equalOrPrefix(acceptedLang, offeredLang string) bool {
 	return acceptedLang == "*" ||
 		offeredLang == "*" ||
@@ -242,4 +470,15 @@ func equalOrWildcard(accepted, offered string) bool {
 //-------------------------------------------------------------------------------------------------
 
 // Debug can be used for observing decisions made by the negotiation algorithm. By default it is no-op.
+//
+// Deprecated: attach a Trace to the request context instead (see WithTrace). Unlike Debug,
+// a Trace is specific to one request and records its decisions in a structured form.
 var Debug = func(string, ...any) {}
+
+// sourceQuality gets the offer's source quality. Zero, e.g. in an Offer literal, means the default.
+func sourceQuality(offer offerpkg.Offer) float64 {
+	if offer.SourceQuality <= 0 {
+		return header.DefaultQuality
+	}
+	return offer.SourceQuality
+}
@@ -12,6 +12,7 @@ import (
 	. "github.com/rickb777/acceptable/headername"
 	"github.com/rickb777/acceptable/offer"
 	"github.com/rickb777/expect"
+	"golang.org/x/text/language"
 )
 
 func Test_should_return_wildcard_data_for_any_language(t *testing.T) {
@@ -31,12 +32,13 @@ func Test_should_return_wildcard_data_for_any_language(t *testing.T) {
 		expect.Value(best.Render).I(lang).Not().ToBeNil(t)
 		best.Render = nil // because functions cannot be compared
 
-		expect.Value(best.Data.Content(dpkg.Chosen{})).I(lang).ToBe(t, someSliceData)
+		expect.Value(best.Data.Content(dpkg.Chosen{}).Next()).I(lang).ToBe(t, someSliceData)
 
 		best.Data = nil // because functions cannot be compared
 		expect.Value(best).I(lang).ToBe(t, &offer.Match{
 			ContentType: header.ContentType{MediaType: "text/test"},
 			Language:    lang,
+			Quality:     1,
 			Charset:     "utf-8",
 			Vary:        []string{Accept, AcceptLanguage},
 		})
@@ -57,6 +59,7 @@ func Test_should_match_subtype_wildcard1(t *testing.T) {
 	expect.Value(best).ToBe(t, &offer.Match{
 		ContentType: header.ContentType{MediaType: "text/test"},
 		Language:    "*",
+		Quality:     1,
 		Charset:     "utf-8",
 		Vary:        []string{Accept},
 	})
@@ -76,6 +79,7 @@ func Test_should_match_subtype_wildcard2(t *testing.T) {
 	expect.Value(best).ToBe(t, &offer.Match{
 		ContentType: header.ContentType{MediaType: "text/test"},
 		Language:    "*",
+		Quality:     1,
 		Charset:     "utf-8",
 		Vary:        []string{Accept},
 	})
@@ -97,8 +101,9 @@ func Test_should_match_language_when_offer_language_is_not_specified(t *testing.
 	expect.Value(best).ToBe(t, &offer.Match{
 		ContentType: header.ContentType{MediaType: "text/html"},
 		Language:    "en",
+		Quality:     1,
 		Charset:     "utf-8",
-		Vary:        []string{Accept, AcceptLanguage},
+		Vary:        []string{Accept, AcceptLanguage, AcceptCharset},
 	})
 }
 
@@ -117,6 +122,7 @@ func Test_should_match_language_wildcard_and_return_selected_language(t *testing
 	expect.Value(best).ToBe(t, &offer.Match{
 		ContentType: header.ContentType{MediaType: "application/octet-stream"},
 		Language:    "en",
+		Quality:     1,
 		Charset:     "utf-8",
 		Vary:        []string{AcceptLanguage},
 	})
@@ -136,12 +142,13 @@ func Test_should_select_language_of_first_matched_offer_when_no_language_matches
 	best := acceptable.BestRequestMatch(req, a, b, c)
 
 	// Then ...
-	expect.Value(best.Data.Content(dpkg.Chosen{})).ToBe(t, someMapData)
+	expect.Value(best.Data.Content(dpkg.Chosen{}).Next()).ToBe(t, someMapData)
 
 	best.Data = nil // because functions cannot be compared
 	expect.Value(best).ToBe(t, &offer.Match{
 		ContentType: header.ContentType{MediaType: "text/html"},
 		Language:    "en",
+		Quality:     1,
 		Charset:     "utf-8",
 		Vary:        []string{Accept, AcceptLanguage},
 	})
@@ -162,6 +169,7 @@ func Test_should_negotiate_a_default_processor(t *testing.T) {
 	expect.Value(best).ToBe(t, &offer.Match{
 		ContentType: header.ContentType{MediaType: "text/plain"},
 		Language:    "*",
+		Quality:     1,
 		Charset:     "utf-8",
 		Vary:        []string{Accept},
 	})
@@ -173,6 +181,7 @@ func Test_should_negotiate_a_default_processor(t *testing.T) {
 	expect.Value(best).ToBe(t, &offer.Match{
 		ContentType: header.ContentType{MediaType: "text/test"},
 		Language:    "*",
+		Quality:     1,
 		Charset:     "utf-8",
 		Vary:        []string{Accept},
 	})
@@ -193,6 +202,7 @@ func Test_should_negotiate_one_of_the_processors(t *testing.T) {
 	expect.Value(best).ToBe(t, &offer.Match{
 		ContentType: header.ContentType{MediaType: "text/a"},
 		Language:    "*",
+		Quality:     1,
 		Charset:     "utf-8",
 		Vary:        []string{Accept},
 	})
@@ -204,11 +214,205 @@ func Test_should_negotiate_one_of_the_processors(t *testing.T) {
 	expect.Value(best).ToBe(t, &offer.Match{
 		ContentType: header.ContentType{MediaType: "text/b"},
 		Language:    "*",
+		Quality:     1,
 		Charset:     "utf-8",
 		Vary:        []string{Accept},
 	})
 }
 
+func Test_should_match_media_type_parameters(t *testing.T) {
+	// Given ...
+	v1 := offer.Of(nil, "application/json;version=1").With("v1", "*")
+	v2 := offer.Of(nil, "application/json;version=2").With("v2", "*")
+
+	cases := []struct {
+		accept, expected string
+	}{
+		{accept: "application/json", expected: "1"},
+		{accept: "application/json;version=2", expected: "2"},
+		{accept: `application/json;version="2"`, expected: "2"},
+		{accept: "application/json, application/json;version=2", expected: "2"},
+		{accept: "application/json;version=1;q=0, application/json", expected: "2"},
+		{accept: "application/*;version=2", expected: "2"},
+		{accept: "application/json;charset=utf-8", expected: "1"},
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add(Accept, c.accept)
+
+		// When ...
+		best := acceptable.BestRequestMatch(req, v1, v2)
+
+		// Then ...
+		expect.Value(best.Data.Content(dpkg.Chosen{}).Next()).I(c.accept).ToBe(t, "v"+c.expected)
+		expect.Value(best.ContentType).I(c.accept).ToBe(t, header.ContentType{
+			MediaType: "application/json",
+			Params:    []header.KV{{Key: "version", Value: c.expected}},
+		})
+	}
+}
+
+func Test_should_not_match_media_type_with_unavailable_parameters(t *testing.T) {
+	// Given ...
+	v1 := offer.Of(nil, "application/json;version=1").With("v1", "*")
+	v2 := offer.Of(nil, "application/json;version=2").With("v2", "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Accept, "application/json;version=3")
+
+	// When ...
+	best := acceptable.BestRequestMatch(req, v1, v2)
+
+	// Then ...
+	expect.Value(best).ToBeNil(t)
+}
+
+func Test_should_rank_offers_by_quality_and_source_quality(t *testing.T) {
+	// Given ...
+	a := offer.Of(nil, "text/csv").With("csv", "*").WithSourceQuality(0.5)
+	b := offer.Of(nil, "application/json").With("json", "*")
+	c := offer.Of(nil, "text/plain").With("txt", "*").WithSourceQuality(0.5)
+
+	cases := []struct {
+		accept, expected string
+	}{
+		{accept: "text/csv, application/json", expected: "json"},
+		{accept: "text/csv, application/json;q=0.4", expected: "csv"},
+		{accept: "text/csv, application/json;q=0.5", expected: "csv"}, // tie, so offer order decides
+		{accept: "text/plain, text/csv", expected: "csv"},             // tie, so offer order decides
+		{accept: "text/plain, text/csv;q=0.9", expected: "txt"},
+		{accept: "text/*", expected: "csv"},
+		{accept: "*/*", expected: "json"},
+	}
+
+	for _, cs := range cases {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add(Accept, cs.accept)
+
+		// When ...
+		best := acceptable.BestRequestMatch(req, a, b, c)
+
+		// Then ...
+		expect.Value(best.Data.Content(dpkg.Chosen{}).Next()).I(cs.accept).ToBe(t, cs.expected)
+	}
+}
+
+func Test_should_treat_zero_source_quality_as_the_default(t *testing.T) {
+	// Given ...
+	a := offer.Offer{ContentType: header.ContentType{MediaType: "text/csv"}}.With("csv", "*")
+	b := offer.Of(nil, "application/json").With("json", "*").WithSourceQuality(0.5)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Accept, "text/csv;q=0.9, application/json")
+
+	// When ...
+	best := acceptable.BestRequestMatch(req, a, b)
+
+	// Then ...
+	expect.Value(best.Data.Content(dpkg.Chosen{}).Next()).ToBe(t, "csv")
+	expect.Number(best.Quality).ToBe(t, 0.9)
+}
+
+func Test_should_use_quality_of_most_specific_media_range(t *testing.T) {
+	// Given ...
+	csv := offer.Of(nil, "text/csv").With("csv", "*")
+	html := offer.Of(nil, "text/html").With("html", "*")
+	plain := offer.Of(nil, "text/plain").With("plain", "*")
+	json := offer.Of(nil, "application/json").With("json", "*")
+	v1 := offer.Of(nil, "application/json;version=1").With("v1", "*")
+	v2 := offer.Of(nil, "application/json;version=2").With("v2", "*")
+
+	cases := []struct {
+		accept    string
+		available []offer.Offer
+		expected  string
+	}{
+		{accept: "text/*;q=0, */*", available: []offer.Offer{csv, json}, expected: "json"},
+		{accept: "text/*;q=0, */*", available: []offer.Offer{csv}, expected: ""},
+		{accept: "text/html;q=0, text/*", available: []offer.Offer{html, plain}, expected: "plain"},
+		{accept: "text/*;q=0.8, text/html;q=0.5", available: []offer.Offer{html, plain}, expected: "plain"},
+		{accept: "text/*, text/html;q=0.5, */*;q=0.1", available: []offer.Offer{json, html}, expected: "html"},
+		{accept: "application/json;version=2;q=0.5, application/json", available: []offer.Offer{v2, v1}, expected: "v1"},
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add(Accept, c.accept)
+
+		// When ...
+		best := acceptable.BestRequestMatch(req, c.available...)
+
+		// Then ...
+		if c.expected == "" {
+			expect.Value(best).I(c.accept).ToBeNil(t)
+		} else {
+			expect.Value(best.Data.Content(dpkg.Chosen{}).Next()).I(c.accept).ToBe(t, c.expected)
+		}
+	}
+}
+
+func Test_should_match_language_by_best_fit(t *testing.T) {
+	n := acceptable.DefaultNegotiator()
+	n.LanguageMatcher = acceptable.BestFit
+
+	// Given ...
+	a := offer.Of(nil, "text/html").With("en", "en").With("zh", "zh-hant").With("es", "es-MX")
+
+	cases := []struct {
+		accLang, lang string
+		confidence    language.Confidence
+	}{
+		{accLang: "zh-TW", lang: "zh-Hant", confidence: language.Exact},
+		{accLang: "es-419, en;q=0.5", lang: "es-MX", confidence: language.High},
+		{accLang: "en-GB", lang: "en", confidence: language.High},
+		{accLang: "fr, en;q=0.1", lang: "en", confidence: language.Exact},
+		{accLang: "*", lang: "en", confidence: language.Exact},
+		{accLang: "fr", lang: "en", confidence: language.No}, // fallback
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add(Accept, "text/html")
+		req.Header.Add(AcceptLanguage, c.accLang)
+
+		// When ...
+		best := n.BestRequestMatch(req, a)
+
+		// Then ...
+		expect.Value(best.Data.Content(dpkg.Chosen{}).Next()).I(c.accLang).ToBe(t, c.lang[:2])
+
+		best.Data = nil // because functions cannot be compared
+		expect.Value(best).I(c.accLang).ToBe(t, &offer.Match{
+			ContentType: header.ContentType{MediaType: "text/html"},
+			Language:    c.lang,
+			Confidence:  c.confidence,
+			Quality:     1,
+			Charset:     "utf-8",
+			Vary:        []string{Accept, AcceptLanguage},
+		})
+	}
+}
+
+func Test_should_match_wildcard_language_by_best_fit(t *testing.T) {
+	n := acceptable.DefaultNegotiator()
+	n.LanguageMatcher = acceptable.BestFit
+
+	// Given ...
+	a := offer.Of(nil, "text/html").With("foo", "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(AcceptLanguage, "pt-br, en;q=0.5")
+
+	// When ...
+	best := n.BestRequestMatch(req, a)
+
+	// Then ...
+	expect.Value(best.Data.Content(dpkg.Chosen{}).Next()).ToBe(t, "foo")
+	expect.String(best.Language).ToBe(t, "pt-BR")
+	expect.Value(best.Confidence).ToBe(t, language.Exact)
+}
+
 func TestMain(m *testing.M) {
 	flag.Parse()
 	//if testing.Verbose() {
@@ -226,3 +430,48 @@ var someSliceData = []string{
 	"hay is for horses",
 	"beef or mutton",
 }
+
+func Test_should_rank_all_acceptable_matches(t *testing.T) {
+	// Given ...
+	a := offer.Of(nil, "text/csv").With("csv-en", "en").With("csv-fr", "fr").WithSourceQuality(0.5)
+	b := offer.Of(nil, "application/json").With("json-en", "en").With("json-fr", "fr")
+	c := offer.Of(nil, "application/xml").With("xml-en", "en")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Accept, "text/csv, application/json;q=0.8, application/xml;q=0")
+	req.Header.Add(AcceptLanguage, "fr, en;q=0.5")
+
+	// When ...
+	ranked := acceptable.RankedRequestMatches(req, a, b, c)
+
+	// Then ...
+	expect.Slice(ranked).ToHaveLength(t, 4)
+
+	var content []any
+	var quality []float64
+	for _, m := range ranked {
+		v, _, _ := m.Data.Content(dpkg.Chosen{Language: m.Language}).Next()
+		content = append(content, v)
+		quality = append(quality, m.Quality)
+		expect.String(m.Charset).ToBe(t, "utf-8")
+	}
+	expect.Slice(content).ToBe(t, "json-fr", "json-en", "csv-fr", "csv-en")
+	expect.Slice(quality).ToBe(t, 0.8, 0.8, 0.5, 0.5)
+
+	best := acceptable.BestRequestMatch(req, a, b, c)
+	expect.Value(best.Data.Content(dpkg.Chosen{Language: best.Language}).Next()).ToBe(t, "json-fr")
+}
+
+func Test_should_rank_no_matches_when_not_acceptable(t *testing.T) {
+	// Given ...
+	a := offer.Of(nil, "text/csv").With("csv", "*").CanHandle406As(http.StatusBadRequest)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Accept, "application/json")
+
+	// When ...
+	ranked := acceptable.RankedRequestMatches(req, a)
+
+	// Then ...
+	expect.Slice(ranked).ToBeEmpty(t)
+}
@@ -0,0 +1,51 @@
+package acceptable
+
+import (
+	"net/http"
+
+	offerpkg "github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/acceptable/templates"
+)
+
+// Negotiator holds the settings used for content negotiation and rendering. Unlike the
+// package-level settings, each Negotiator is independent, so different services within
+// one program (or parallel tests) can use different settings.
+//
+// The package-level functions BestRequestMatch, RankedRequestMatches and RenderBestMatch
+// all use DefaultNegotiator.
+type Negotiator struct {
+	// LanguageMatcher selects the language matching algorithm.
+	LanguageMatcher LanguageMatching
+
+	// NoMatchAccepted is used by RenderBestMatch when no match has been found. If it is nil,
+	// a plain 406-Not Acceptable response is sent.
+	NoMatchAccepted func(rw http.ResponseWriter, req *http.Request)
+
+	// Debug, if not nil, can be used for observing decisions made by the negotiation algorithm.
+	// Attaching a Trace to the request context is usually more useful (see WithTrace).
+	Debug func(string, ...any)
+
+	// Offers holds the settings for constructing offers, e.g. n.Offers.JSON().
+	Offers offerpkg.Config
+
+	// Templates holds the settings for template processing, e.g. n.Templates.TextHtmlOffer(...).
+	Templates templates.Config
+}
+
+// DefaultNegotiator returns a Negotiator holding the current package-level settings,
+// i.e. LanguageMatcher, NoMatchAccepted and Debug in this package, plus those in the
+// offer and templates packages.
+func DefaultNegotiator() *Negotiator {
+	return &Negotiator{
+		LanguageMatcher: LanguageMatcher,
+		NoMatchAccepted: NoMatchAccepted,
+		Debug:           Debug,
+		Offers:          offerpkg.DefaultConfig(),
+		Templates:       templates.DefaultConfig(),
+	}
+}
+
+// RenderBestMatch is as per the RenderBestMatch function, using this negotiator's settings.
+func (n *Negotiator) RenderBestMatch(rw http.ResponseWriter, req *http.Request, available ...offerpkg.Offer) error {
+	return RespondWith{Negotiator: n}.RenderBestMatch(rw, req, available...)
+}
@@ -0,0 +1,80 @@
+package acceptable_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rickb777/acceptable"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/acceptable/templates"
+	"github.com/rickb777/expect"
+)
+
+func Test_negotiator_should_use_its_own_no_match_handler(t *testing.T) {
+	// Given ...
+	n := acceptable.DefaultNegotiator()
+	n.NoMatchAccepted = func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusTeapot)
+	}
+
+	a := n.Offers.JSON().With("foo", "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Accept, "image/png")
+	w1 := httptest.NewRecorder()
+	w2 := httptest.NewRecorder()
+
+	// When ...
+	err1 := n.RenderBestMatch(w1, req, a)
+	err2 := acceptable.RenderBestMatch(w2, req, a)
+
+	// Then ...
+	expect.Error(err1).Not().ToHaveOccurred(t)
+	expect.Number(w1.Code).ToBe(t, http.StatusTeapot)
+
+	expect.Error(err2).Not().ToHaveOccurred(t)
+	expect.Number(w2.Code).ToBe(t, http.StatusNotAcceptable)
+}
+
+func Test_negotiator_should_use_its_own_language_matcher(t *testing.T) {
+	// Given ...
+	n := &acceptable.Negotiator{LanguageMatcher: acceptable.BestFit}
+
+	a := offer.Of(nil, "text/html").With("en", "en")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Accept, "text/html")
+	req.Header.Add(AcceptLanguage, "en-GB")
+
+	// When ...
+	best1 := n.BestRequestMatch(req, a)
+	best2 := acceptable.BestRequestMatch(req, a)
+
+	// Then ...
+	expect.Number(best1.Confidence).Not().ToBe(t, 0)
+	expect.Number(best2.Confidence).ToBe(t, 0)
+}
+
+func Test_negotiator_should_render_using_its_own_configuration(t *testing.T) {
+	// Given ...
+	n := &acceptable.Negotiator{
+		Templates: templates.Config{DefaultPage: "home.html"},
+	}
+
+	a := n.Templates.TextHtmlOffer("examples/templates/en", ".html", nil).
+		With(map[string]any{"Proclamation": "A Title"}, "en")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Accept, "text/html")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RespondWith{Negotiator: n}.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Body.String()).ToContain(t, "<h1>Home.</h1>")
+}
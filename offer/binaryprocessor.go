@@ -11,10 +11,16 @@ import (
 )
 
 // ImageJPEG is an Offer for image/jpeg content using BinaryProcessor.
-func ImageJPEG() Offer { return Of(BinaryProcessor(0), contenttype.ImageJPEG) }
+func ImageJPEG() Offer { return binary(contenttype.ImageJPEG) }
 
 // ImagePNG is an Offer for image/png content using BinaryProcessor.
-func ImagePNG() Offer { return Of(BinaryProcessor(0), contenttype.ImagePNG) }
+func ImagePNG() Offer { return binary(contenttype.ImagePNG) }
+
+func binary(contentType string) Offer {
+	o := of(BinaryProcessor(0), contentType)
+	o.RawContent = true
+	return o
+}
 
 // BinaryProcessor creates an output processor that outputs binary data in a form suitable for image/* and similar responses.
 // Model values should be one of the following:
@@ -25,20 +31,27 @@ func ImagePNG() Offer { return Of(BinaryProcessor(0), contenttype.ImagePNG) }
 // * nil
 //
 // Because it handles io.Reader and io.WriterTo, BinaryProcessor can be used to stream large responses (without any
-// further encoding).
+// further encoding). An io.ReadSeeker also allows byte range requests to be served efficiently (see
+// acceptable.RespondWith.AcceptRanges), provided that the offer's RawContent is set.
 //
 // GZIP compression-on-demand is enabled when gzipLevel is non-zero.
 func BinaryProcessor(gzipLevel int) Processor {
-	return GZIPProcessor(gzipLevel, binaryProcessor())
+	return EncodingProcessor(gzipLevel, binaryProcessor())
 }
 
 func binaryProcessor() Processor {
 	return func(w io.Writer, _ *http.Request, data dpkg.Data, chosen dpkg.Chosen) (err error) {
 		more := data != nil
 
+		var content dpkg.Cursor
+		if more {
+			content = data.Content(chosen)
+			defer content.Close()
+		}
+
 		for more {
 			var d any
-			d, more, err = data.Content(chosen)
+			d, more, err = content.Next()
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,93 @@
+package offer
+
+import (
+	"io"
+	"net/http"
+
+	dpkg "github.com/rickb777/acceptable/data"
+)
+
+// CBOR constructs a CBOR (RFC-8949) Offer easily. NewCBOREncoder must have been set.
+// The response will be compressed (see [GZIPLevel]) when the client accepts a content coding such as gzip.
+func CBOR() Offer {
+	return DefaultConfig().CBOR()
+}
+
+// CBORProcessor creates a new processor for CBOR. This converts a data item into CBOR using
+// NewCBOREncoder. A sequence of data items is written as a CBOR sequence (RFC-8742), i.e. the
+// encoded items are concatenated. A single nil value is encoded as CBOR null, but nil items
+// in a longer sequence are skipped.
+func CBORProcessor(gzipLevel int) Processor {
+	mustHaveEncoder(NewCBOREncoder != nil, "NewCBOREncoder")
+	return EncodingProcessor(gzipLevel, cborProcessor(defaultCBOREncoder))
+}
+
+// defaultCBOREncoder defers to NewCBOREncoder at the time of use.
+func defaultCBOREncoder(w io.Writer) CBOREncoder { return NewCBOREncoder(w) }
+
+// mustHaveEncoder panics when a pluggable encoder has not been provided; this is a misconfiguration.
+func mustHaveEncoder(present bool, name string) {
+	if !present {
+		panic("offer." + name + " has not been set (see its documentation)")
+	}
+}
+
+func cborProcessor(newEncoder func(w io.Writer) CBOREncoder) Processor {
+	return concatenatedProcessor(func(w io.Writer) func(any) error { return newEncoder(w).Encode })
+}
+
+// CBOREncoder summarises the key method of the CBOR encoder.
+type CBOREncoder interface {
+	Encode(any) error
+}
+
+// NewCBOREncoder is a pluggable CBOR encoder. It is nil initially, so that this package does
+// not depend on any particular CBOR library; it must be set before CBOR offers are constructed,
+// e.g. using github.com/fxamacker/cbor/v2
+//
+//	offer.NewCBOREncoder = func(w io.Writer) offer.CBOREncoder { return cbor.NewEncoder(w) }
+//
+// Config.NewCBOREncoder can be used instead for independent settings.
+var NewCBOREncoder func(w io.Writer) CBOREncoder
+
+//-------------------------------------------------------------------------------------------------
+
+// concatenatedProcessor writes each data item using a self-delimiting binary encoding, so that a
+// sequence is simply the concatenation of its encoded items.
+func concatenatedProcessor(newEncoder func(w io.Writer) func(any) error) Processor {
+	return func(w io.Writer, _ *http.Request, data dpkg.Data, chosen dpkg.Chosen) (err error) {
+		encode := newEncoder(w)
+
+		content := data.Content(chosen)
+		defer content.Close()
+
+		next := dpkg.Stream(content)
+		item, ok, err := next()
+		if err != nil || !ok {
+			return err // an empty sequence has an empty body
+		}
+
+		if item == nil {
+			// a lone nil value is encoded; nil items in a longer sequence are skipped
+			item, ok, err = next()
+			if err != nil {
+				return err
+			} else if !ok {
+				return encode(nil)
+			}
+		}
+
+		for {
+			if item != nil {
+				if err = encode(item); err != nil {
+					return err
+				}
+			}
+
+			item, ok, err = next()
+			if err != nil || !ok {
+				return err
+			}
+		}
+	}
+}
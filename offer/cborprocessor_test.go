@@ -0,0 +1,130 @@
+package offer_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/rickb777/acceptable/contenttype"
+	dpkg "github.com/rickb777/acceptable/data"
+	"github.com/rickb777/acceptable/header"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+// cborEncoder writes real CBOR (RFC-8949) but only for the few small values used in these tests.
+type cborEncoder struct {
+	w io.Writer
+}
+
+func (e cborEncoder) Encode(v any) error {
+	var b []byte
+	switch x := v.(type) {
+	case nil:
+		b = []byte{0xf6} // null
+	case int:
+		b = []byte{byte(x)} // unsigned integer 0..23
+	case string:
+		b = append([]byte{0x60 | byte(len(x))}, x...) // text string up to 23 bytes
+	default:
+		return fmt.Errorf("unsupported %T", v)
+	}
+	_, err := e.w.Write(b)
+	return err
+}
+
+func newCBOREncoder(w io.Writer) offer.CBOREncoder { return cborEncoder{w: w} }
+
+func values(v ...any) dpkg.Data {
+	return dpkg.Seq(slices.Values(v))
+}
+
+// Both offers concatenate their encoded items, so they share the same cases;
+// the expected bodies are shown in hex.
+func TestConcatenatedOffersShouldWriteResponseBody(t *testing.T) {
+	offer.NewCBOREncoder = newCBOREncoder
+	offer.NewMsgPackEncoder = newMsgPackEncoder
+	defer func() {
+		offer.NewCBOREncoder = nil
+		offer.NewMsgPackEncoder = nil
+	}()
+
+	cases := []struct {
+		data    dpkg.Data
+		cbor    string
+		msgPack string
+	}{
+		{data: dpkg.Of(1), cbor: "01", msgPack: "01"},
+		{data: dpkg.Of("ab"), cbor: "62 61 62", msgPack: "a2 61 62"},
+		{data: dpkg.Of(nil), cbor: "f6", msgPack: "c0"},
+		{data: values(nil), cbor: "f6", msgPack: "c0"},
+		{data: values(1, "ab"), cbor: "01 62 61 62", msgPack: "01 a2 61 62"},
+		{data: values(nil, 2, nil, 3), cbor: "02 03", msgPack: "02 03"},
+		{data: values(), cbor: "", msgPack: ""},
+	}
+
+	for i, c := range cases {
+		offers := []struct {
+			offer       offer.Offer
+			contentType string
+			expected    string
+		}{
+			{offer: offer.CBOR(), contentType: contenttype.ApplicationCBOR, expected: c.cbor},
+			{offer: offer.MsgPack(), contentType: contenttype.ApplicationMsgPack, expected: c.msgPack},
+		}
+
+		for _, f := range offers {
+			req := &http.Request{}
+			rw := httptest.NewRecorder()
+
+			o := f.offer.With(c.data, "*")
+			m := o.BuildMatch(o.ContentType, "*")
+
+			w := m.ApplyHeaders(rw)
+			err := m.Render(w, req, m.Data, dpkg.Chosen{})
+			w.Close()
+
+			expect.Error(err).I(i).Info(f.contentType).Not().ToHaveOccurred(t)
+			expect.String(rw.Header().Get(ContentType)).I(i).ToBe(t, f.contentType)
+			expect.String(fmt.Sprintf("% x", rw.Body.Bytes())).I(i).Info(f.contentType).ToBe(t, f.expected)
+		}
+	}
+}
+
+func TestConfig_should_use_its_own_binary_encoders(t *testing.T) {
+	cfg := offer.Config{
+		NewCBOREncoder:    newCBOREncoder,
+		NewMsgPackEncoder: newMsgPackEncoder,
+	}
+
+	processors := []struct {
+		p        offer.Processor
+		expected string
+	}{
+		{p: cfg.CBOR().BuildMatch(header.ContentType{}, "*").Render, expected: "01 62 61 62"},
+		{p: cfg.MsgPack().BuildMatch(header.ContentType{}, "*").Render, expected: "01 a2 61 62"},
+		{p: cfg.CBORProcessor(), expected: "01 62 61 62"},
+		{p: cfg.MsgPackProcessor(), expected: "01 a2 61 62"},
+	}
+
+	for i, c := range processors {
+		req := &http.Request{}
+		rw := httptest.NewRecorder()
+
+		err := c.p(rw, req, values(1, "ab"), dpkg.Chosen{})
+
+		expect.Error(err).I(i).Not().ToHaveOccurred(t)
+		expect.String(fmt.Sprintf("% x", rw.Body.Bytes())).I(i).ToBe(t, c.expected)
+	}
+}
+
+func TestCBOR_should_panic_without_an_encoder(t *testing.T) {
+	defer func() {
+		expect.Any(recover()).Not().ToBeNil(t)
+	}()
+	offer.CBOR()
+}
@@ -0,0 +1,102 @@
+package offer
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/rickb777/acceptable/header"
+	"github.com/rickb777/acceptable/headername"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// WithCharsets declares the character sets that the offer supports, in order of preference.
+// Each must be known to golang.org/x/text/encoding/htmlindex; otherwise this method panics.
+// The names are held in their canonical form, so for example "iso-8859-1" becomes
+// "windows-1252" (as per the WHATWG encoding standard).
+//
+// If no charsets are declared, the offer supports utf-8 plus any charset that can be
+// transcoded using htmlindex.
+func (o Offer) WithCharsets(charsets ...string) Offer {
+	o.Charsets = make([]string, 0, len(charsets))
+	for _, cs := range charsets {
+		name, ok := charsetName(cs)
+		if !ok {
+			panic(fmt.Sprintf("charset %q is not supported", cs))
+		}
+		if !slices.Contains(o.Charsets, name) {
+			o.Charsets = append(o.Charsets, name)
+		}
+	}
+	return o
+}
+
+// NegotiateCharset chooses the character set for the response to a request, based on its
+// Accept-Charset header and the charsets supported by the offer. The charset with the highest
+// quality is chosen, utf-8 being preferred whenever it is equally acceptable. The result is
+// not acceptable (false) when none of the offer's charsets is acceptable.
+//
+// Charset negotiation only applies to textual media types; for others the result is always utf-8.
+func (o Offer) NegotiateCharset(req *http.Request) (charset string, acceptable bool) {
+	if !o.IsTextual() {
+		return utf8, true
+	}
+
+	accepted := header.ParsePrecedenceValues(req.Header.Get(headername.AcceptCharset))
+
+	candidates := o.Charsets
+	if len(candidates) == 0 {
+		candidates = []string{utf8}
+		for _, pv := range accepted {
+			if name, ok := charsetName(pv.Value); ok && !slices.Contains(candidates, name) {
+				candidates = append(candidates, name)
+			}
+		}
+	}
+
+	if len(accepted) == 0 {
+		// all charsets are acceptable
+		if slices.Contains(candidates, utf8) {
+			return utf8, true
+		}
+		return candidates[0], true
+	}
+
+	bestQ := 0.0
+	for _, cs := range candidates {
+		q := charsetQuality(accepted, cs)
+		if q > bestQ || (q > 0 && q == bestQ && cs == utf8) {
+			charset = cs
+			bestQ = q
+		}
+	}
+
+	return charset, charset != ""
+}
+
+// charsetQuality gets the quality of a charset, which is explicitly listed or is implied
+// by the "*" wildcard. Zero is returned otherwise.
+func charsetQuality(accepted header.PrecedenceValues, charset string) float64 {
+	wildcard := 0.0
+	for _, pv := range accepted {
+		if pv.Value == "*" {
+			wildcard = pv.Quality
+		} else if name, ok := charsetName(pv.Value); ok && name == charset {
+			return pv.Quality
+		}
+	}
+	return wildcard
+}
+
+// charsetName gets the canonical name of a charset, provided that it can be used for transcoding.
+func charsetName(label string) (string, bool) {
+	enc, err := htmlindex.Get(label)
+	if err != nil || enc == encoding.Replacement {
+		return "", false
+	}
+	name, err := htmlindex.Name(enc)
+	return name, err == nil
+}
+
+const utf8 = "utf-8"
@@ -0,0 +1,60 @@
+package offer_test
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+func TestNegotiateCharset(t *testing.T) {
+	text := offer.Of(nil, "text/plain")
+	latin := text.WithCharsets("iso-8859-1")
+	latinOrUTF8 := text.WithCharsets("iso-8859-1", "utf-8")
+
+	cases := []struct {
+		o             offer.Offer
+		acceptCharset string
+		charset       string
+		acceptable    bool
+	}{
+		{o: text, acceptCharset: "", charset: "utf-8", acceptable: true},
+		{o: text, acceptCharset: "utf-8", charset: "utf-8", acceptable: true},
+		{o: text, acceptCharset: "utf8", charset: "utf-8", acceptable: true},
+		{o: text, acceptCharset: "iso-8859-1", charset: "windows-1252", acceptable: true},
+		{o: text, acceptCharset: "iso-8859-1, utf-8", charset: "utf-8", acceptable: true},
+		{o: text, acceptCharset: "iso-8859-1, utf-8;q=0.5", charset: "windows-1252", acceptable: true},
+		{o: text, acceptCharset: "utf-8;q=0.5, iso-8859-1;q=0.5", charset: "utf-8", acceptable: true},
+		{o: text, acceptCharset: "*", charset: "utf-8", acceptable: true},
+		{o: text, acceptCharset: "unknown", charset: "", acceptable: false},
+		{o: text, acceptCharset: "utf-8;q=0, iso-8859-1;q=0.1", charset: "windows-1252", acceptable: true},
+		{o: latin, acceptCharset: "", charset: "windows-1252", acceptable: true},
+		{o: latin, acceptCharset: "utf-8", charset: "", acceptable: false},
+		{o: latin, acceptCharset: "utf-8, *;q=0.1", charset: "windows-1252", acceptable: true},
+		{o: latinOrUTF8, acceptCharset: "", charset: "utf-8", acceptable: true},
+		{o: latinOrUTF8, acceptCharset: "koi8-r", charset: "", acceptable: false},
+		{o: offer.Of(nil, "image/png"), acceptCharset: "iso-8859-1", charset: "utf-8", acceptable: true},
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if c.acceptCharset != "" {
+			req.Header.Set(AcceptCharset, c.acceptCharset)
+		}
+
+		charset, acceptable := c.o.NegotiateCharset(req)
+
+		expect.String(charset).I(c.o, c.acceptCharset).ToBe(t, c.charset)
+		expect.Bool(acceptable).I(c.o, c.acceptCharset).ToBe(t, c.acceptable)
+	}
+}
+
+func TestWithCharsets_should_panic_if_charset_is_unknown(t *testing.T) {
+	defer func() {
+		expect.Any(recover()).Not().ToBeNil(t)
+	}()
+
+	offer.Of(nil, "text/plain").WithCharsets("utf-8", "unknown")
+}
@@ -0,0 +1,182 @@
+package offer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rickb777/acceptable/contenttype"
+)
+
+// Config holds the settings used for constructing offers and their processors. Unlike
+// the package-level settings GZIPLevel and NewJSONEncoder, each Config is independent,
+// so different parts of a program (or parallel tests) can use different settings.
+//
+// The zero value is usable: it has no compression and uses the default encoders.
+type Config struct {
+	// GZIPLevel sets the compression strength when a content coding such as gzip is applied
+	// to a response entity (see the package-level GZIPLevel and Offer.CompressionLevel).
+	GZIPLevel int
+
+	// NewJSONEncoder provides the JSON encoder. If nil, the package-level NewJSONEncoder
+	// is used.
+	NewJSONEncoder func(w io.Writer) JSONEncoder
+
+	// NewYAMLEncoder provides the YAML encoder. If nil, the package-level NewYAMLEncoder
+	// is used; one or other must be set for YAML offers.
+	NewYAMLEncoder func(w io.Writer) YAMLEncoder
+
+	// NewCBOREncoder provides the CBOR encoder. If nil, the package-level NewCBOREncoder
+	// is used; one or other must be set for CBOR offers.
+	NewCBOREncoder func(w io.Writer) CBOREncoder
+
+	// NewMsgPackEncoder provides the MessagePack encoder. If nil, the package-level
+	// NewMsgPackEncoder is used; one or other must be set for MessagePack offers.
+	NewMsgPackEncoder func(w io.Writer) MsgPackEncoder
+
+	// Compressors holds the content codings that can be used for compressing responses. If
+	// nil, the package-level Compressors is used.
+	Compressors map[string]Compressor
+
+	// ContentCodingPreference lists content codings in the server's order of preference. If
+	// nil, the package-level ContentCodingPreference is used.
+	ContentCodingPreference []string
+}
+
+// DefaultConfig returns a Config holding the current package-level settings.
+func DefaultConfig() Config {
+	return Config{GZIPLevel: GZIPLevel}
+}
+
+// JSON constructs a JSON Offer using this configuration.
+func (c Config) JSON(indent ...string) Offer {
+	return c.of(jsonProcessor(c.jsonEncoder(), indent...), contenttype.ApplicationJSON).WithCompressionLevel(c.GZIPLevel)
+}
+
+// JSONProcessor creates a new processor for JSON using this configuration (see JSONProcessor).
+func (c Config) JSONProcessor(indent ...string) Processor {
+	return c.EncodingProcessor(c.GZIPLevel, jsonProcessor(c.jsonEncoder(), indent...))
+}
+
+// of constructs an Offer for one of this package's processors using this configuration's
+// content codings.
+func (c Config) of(processor Processor, contentType string) Offer {
+	o := of(processor, contentType)
+	o.codings = c.codings()
+	return o
+}
+
+func (c Config) codings() contentCodings {
+	return contentCodings{compressors: c.Compressors, preference: c.ContentCodingPreference}
+}
+
+// JSONSeq constructs a JSON text sequence Offer using this configuration (see JSONSeq).
+func (c Config) JSONSeq() Offer {
+	return c.of(jsonSeqProcessor(c.jsonEncoder()), contenttype.ApplicationJSONSeq).WithCompressionLevel(c.GZIPLevel)
+}
+
+// JSONSeqProcessor creates a new processor for JSON text sequences using this configuration
+// (see JSONSeqProcessor).
+func (c Config) JSONSeqProcessor() Processor {
+	return c.EncodingProcessor(c.GZIPLevel, jsonSeqProcessor(c.jsonEncoder()))
+}
+
+// NDJSON constructs a newline-delimited JSON Offer using this configuration (see NDJSON).
+func (c Config) NDJSON(batch ...int) Offer {
+	return c.of(ndjsonProcessor(c.jsonEncoder(), batch...), contenttype.ApplicationNDJSON).WithCompressionLevel(c.GZIPLevel)
+}
+
+// NDJSONProcessor creates a new processor for newline-delimited JSON using this configuration
+// (see NDJSONProcessor).
+func (c Config) NDJSONProcessor(batch ...int) Processor {
+	return c.EncodingProcessor(c.GZIPLevel, ndjsonProcessor(c.jsonEncoder(), batch...))
+}
+
+// EventStream constructs a server-sent events Offer using this configuration (see EventStream).
+// The default event encoder uses this configuration's JSON encoder.
+func (c Config) EventStream(encoder ...EventEncoder) Offer {
+	return c.of(eventStreamProcessor(defaultEventEncoder(c.jsonEncoder()), encoder...), contenttype.TextEventStream).WithCharsets("utf-8")
+}
+
+func (c Config) jsonEncoder() func(w io.Writer) JSONEncoder {
+	if c.NewJSONEncoder == nil {
+		return defaultJSONEncoder
+	}
+	return c.NewJSONEncoder
+}
+
+// YAML constructs a YAML Offer using this configuration.
+func (c Config) YAML(style ...YAMLSequence) Offer {
+	return c.of(yamlProcessor(c.yamlEncoder(), style...), contenttype.ApplicationYAML).WithCompressionLevel(c.GZIPLevel)
+}
+
+// YAMLProcessor creates a new processor for YAML using this configuration (see YAMLProcessor).
+func (c Config) YAMLProcessor(style ...YAMLSequence) Processor {
+	return c.EncodingProcessor(c.GZIPLevel, yamlProcessor(c.yamlEncoder(), style...))
+}
+
+func (c Config) yamlEncoder() func(w io.Writer) YAMLEncoder {
+	if c.NewYAMLEncoder == nil {
+		mustHaveEncoder(NewYAMLEncoder != nil, "NewYAMLEncoder")
+		return defaultYAMLEncoder
+	}
+	return c.NewYAMLEncoder
+}
+
+// CBOR constructs a CBOR Offer using this configuration.
+func (c Config) CBOR() Offer {
+	return c.of(cborProcessor(c.cborEncoder()), contenttype.ApplicationCBOR).WithCompressionLevel(c.GZIPLevel)
+}
+
+// CBORProcessor creates a new processor for CBOR using this configuration (see CBORProcessor).
+func (c Config) CBORProcessor() Processor {
+	return c.EncodingProcessor(c.GZIPLevel, cborProcessor(c.cborEncoder()))
+}
+
+func (c Config) cborEncoder() func(w io.Writer) CBOREncoder {
+	if c.NewCBOREncoder == nil {
+		mustHaveEncoder(NewCBOREncoder != nil, "NewCBOREncoder")
+		return defaultCBOREncoder
+	}
+	return c.NewCBOREncoder
+}
+
+// MsgPack constructs a MessagePack Offer using this configuration.
+func (c Config) MsgPack() Offer {
+	return c.of(msgPackProcessor(c.msgPackEncoder()), contenttype.ApplicationMsgPack).WithCompressionLevel(c.GZIPLevel)
+}
+
+// MsgPackProcessor creates a new processor for MessagePack using this configuration (see
+// MsgPackProcessor).
+func (c Config) MsgPackProcessor() Processor {
+	return c.EncodingProcessor(c.GZIPLevel, msgPackProcessor(c.msgPackEncoder()))
+}
+
+func (c Config) msgPackEncoder() func(w io.Writer) MsgPackEncoder {
+	if c.NewMsgPackEncoder == nil {
+		mustHaveEncoder(NewMsgPackEncoder != nil, "NewMsgPackEncoder")
+		return defaultMsgPackEncoder
+	}
+	return c.NewMsgPackEncoder
+}
+
+// XML constructs an XML Offer using this configuration.
+func (c Config) XML(root string, indent ...string) Offer {
+	return c.of(xmlProcessor(root, indent...), contenttype.ApplicationXML).WithCompressionLevel(c.GZIPLevel)
+}
+
+// CSV constructs a CSV Offer using this configuration.
+func (c Config) CSV(comma ...rune) Offer {
+	return c.of(csvProcessor(comma...), contenttype.TextCSV).WithCompressionLevel(c.GZIPLevel)
+}
+
+// Text returns an Offer for text/subtype content using this configuration.
+func (c Config) Text(subtype string) Offer {
+	if strings.ContainsRune(subtype, '/') {
+		panic(fmt.Sprintf("subtype %q must not contain '/'", subtype))
+	}
+	return c.of(txtProcessor(), "text/"+subtype).WithCompressionLevel(c.GZIPLevel)
+}
+
+// TextPlain returns an Offer for text/plain content using this configuration.
+func (c Config) TextPlain() Offer { return c.Text("plain") }
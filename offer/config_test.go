@@ -0,0 +1,138 @@
+package offer_test
+
+import (
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dpkg "github.com/rickb777/acceptable/data"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+type fakeJSONEncoder struct {
+	w io.Writer
+}
+
+func (e fakeJSONEncoder) SetIndent(string, string) {}
+
+func (e fakeJSONEncoder) Encode(v any) error {
+	_, err := io.WriteString(e.w, "fake\n")
+	return err
+}
+
+func TestConfig_should_use_its_own_json_encoder(t *testing.T) {
+	cfg := offer.Config{
+		NewJSONEncoder: func(w io.Writer) offer.JSONEncoder { return fakeJSONEncoder{w: w} },
+	}
+
+	o := cfg.JSON().With("foo", "*")
+	m := o.BuildMatch(o.ContentType, "*")
+
+	req := &http.Request{}
+	rw := httptest.NewRecorder()
+
+	err := m.Render(rw, req, m.Data, dpkg.Chosen{})
+
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Body.String()).ToBe(t, "fake\n")
+}
+
+func TestConfig_should_use_its_own_json_encoder_for_json_seq(t *testing.T) {
+	cfg := offer.Config{
+		NewJSONEncoder: func(w io.Writer) offer.JSONEncoder { return fakeJSONEncoder{w: w} },
+	}
+
+	p := cfg.JSONSeqProcessor()
+
+	req := &http.Request{}
+	rw := httptest.NewRecorder()
+
+	err := p(rw, req, dpkg.Of("foo"), dpkg.Chosen{})
+
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Body.String()).ToBe(t, "\x1efake\n")
+}
+
+func TestConfig_should_use_its_own_gzip_level(t *testing.T) {
+	cases := []struct {
+		cfg      offer.Config
+		encoding string
+	}{
+		{cfg: offer.Config{GZIPLevel: offer.NoCompression}, encoding: ""},
+		{cfg: offer.Config{GZIPLevel: offer.MidCompression}, encoding: "gzip"},
+	}
+
+	for _, c := range cases {
+		o := c.cfg.TextPlain().With("foo", "*")
+		expect.Number(o.CompressionLevel).ToBe(t, c.cfg.GZIPLevel)
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set(AcceptEncoding, "gzip")
+		rw := httptest.NewRecorder()
+
+		m := o.BuildMatch(o.ContentType, "*")
+		m.ContentEncoding, _ = offer.NegotiateContentCoding(req, o.CompressionLevel > 0)
+
+		w := m.ApplyHeaders(rw)
+		err := m.Render(w, req, m.Data, dpkg.Chosen{})
+		w.Close()
+
+		expect.Error(err).Not().ToHaveOccurred(t)
+		expect.String(rw.Header().Get(ContentEncoding)).I(c.encoding).ToBe(t, c.encoding)
+	}
+}
+
+func TestConfig_should_reject_an_invalid_gzip_level_when_constructing_processors(t *testing.T) {
+	defer func() {
+		expect.Any(recover()).Not().ToBeNil(t)
+	}()
+	offer.Config{GZIPLevel: 10}.JSONProcessor()
+}
+
+func TestConfig_should_use_its_own_compressors(t *testing.T) {
+	cfg := offer.Config{
+		GZIPLevel: offer.MidCompression,
+		Compressors: map[string]offer.Compressor{
+			"br": func(w io.Writer, level int) (io.WriteCloser, error) {
+				return zlib.NewWriterLevel(w, level) // a stand-in for Brotli
+			},
+		},
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(AcceptEncoding, "gzip, br")
+
+	// the package-level compressors are unaffected
+	coding, _ := offer.NegotiateContentCoding(req, true)
+	expect.String(coding).ToBe(t, "gzip")
+
+	o := cfg.TextPlain().With("foo", "*")
+	coding, acceptable := o.NegotiateContentCoding(req)
+	expect.String(coding).ToBe(t, "br")
+	expect.Bool(acceptable).ToBeTrue(t)
+
+	rw := httptest.NewRecorder()
+	m := o.BuildMatch(o.ContentType, "*")
+	m.ContentEncoding = coding
+
+	w := m.ApplyHeaders(rw)
+	err := m.Render(w, req, m.Data, dpkg.Chosen{})
+	w.Close()
+
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Header().Get(ContentEncoding)).ToBe(t, "br")
+
+	// the processor also uses the configured compressors
+	req.Header.Set(AcceptEncoding, "gzip")
+	rw = httptest.NewRecorder()
+
+	err = cfg.JSONProcessor()(rw, req, dpkg.Of("foo"), dpkg.Chosen{})
+
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Header().Get(ContentEncoding)).ToBe(t, "")
+	expect.String(rw.Body.String()).ToBe(t, "\"foo\"\n")
+}
@@ -7,14 +7,13 @@ import (
 	"net/http"
 	"reflect"
 
-	"github.com/rickb777/acceptable/contenttype"
 	dpkg "github.com/rickb777/acceptable/data"
 )
 
 // CSV constructs a CSV Offer easily.
-// The response will use gzip compression (see [GZIPLevel]) when the client requests it.
+// The response will be compressed (see [GZIPLevel]) when the client accepts a content coding such as gzip.
 func CSV(comma ...rune) Offer {
-	return Of(CSVProcessor(GZIPLevel, comma...), contenttype.TextCSV)
+	return DefaultConfig().CSV(comma...)
 }
 
 // CSVProcessor creates an output processor that serialises a dataModel in CSV form. With no arguments, the default
@@ -39,7 +38,7 @@ func CSV(comma ...rune) Offer {
 //
 // * []struct for some struct in which all the fields are exported and of simple types (as above), written as many rows
 func CSVProcessor(gzipLevel int, comma ...rune) Processor {
-	return GZIPProcessor(gzipLevel, csvProcessor(comma...))
+	return EncodingProcessor(gzipLevel, csvProcessor(comma...))
 }
 
 func csvProcessor(comma ...rune) Processor {
@@ -54,9 +53,15 @@ func csvProcessor(comma ...rune) Processor {
 
 		more := data != nil
 
+		var content dpkg.Cursor
+		if more {
+			content = data.Content(chosen)
+			defer content.Close()
+		}
+
 		for more {
 			var d any
-			d, more, err = data.Content(chosen)
+			d, more, err = content.Next()
 			if err != nil {
 				return err
 			}
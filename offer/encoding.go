@@ -0,0 +1,172 @@
+package offer
+
+import (
+	gzippkg "compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/rickb777/acceptable/header"
+	"github.com/rickb777/acceptable/headername"
+)
+
+// Compressor creates a writer that compresses everything written to it using the given
+// compression level, writing the result to w. The writer is closed after the response
+// has been rendered.
+type Compressor func(w io.Writer, level int) (io.WriteCloser, error)
+
+// Compressors holds the content codings that can be used for compressing responses, keyed
+// by their names (see RFC-9110 section 8.4.1). The gzip and deflate codings are provided
+// using the standard library. Others, such as "br" (Brotli) and "zstd", need other libraries
+// and can be added during startup, e.g.
+//
+//	offer.Compressors["br"] = func(w io.Writer, level int) (io.WriteCloser, error) {
+//	    return brotli.NewWriterLevel(w, level), nil
+//	}
+//
+// The compression level is that of the offer (see Offer.CompressionLevel); this is in the
+// range used by compress/gzip, so it may need to be adjusted to suit the compressor.
+//
+// This is the default for Config.Compressors.
+var Compressors = map[string]Compressor{
+	gzip: func(w io.Writer, level int) (io.WriteCloser, error) {
+		return gzippkg.NewWriterLevel(w, level)
+	},
+	deflate: func(w io.Writer, level int) (io.WriteCloser, error) {
+		// the "deflate" coding is the zlib format (RFC-9110 section 8.4.1.2)
+		return zlib.NewWriterLevel(w, level)
+	},
+}
+
+// ContentCodingPreference lists content codings in the server's order of preference. This
+// decides between codings that the client rates equally. Any other codings in Compressors
+// are less preferred than these.
+//
+// This is the default for Config.ContentCodingPreference.
+var ContentCodingPreference = []string{br, zstd, gzip, deflate}
+
+// NegotiateContentCoding chooses the content coding for the response to a request, based on
+// its Accept-Encoding header and following RFC-9110 section 12.5.3. If compress is false,
+// only the identity coding (i.e. no compression) is available; otherwise, the codings in
+// Compressors are also available.
+//
+// The chosen coding is returned, or "" for identity. The result is not acceptable (false)
+// when identity has been refused, either explicitly or via "*;q=0", and no other coding
+// is available.
+func NegotiateContentCoding(req *http.Request, compress bool) (coding string, acceptable bool) {
+	return contentCodings{}.negotiate(req, compress)
+}
+
+// NegotiateContentCoding chooses the content coding for the response to a request, based on
+// its Accept-Encoding header and the content codings available to the offer (see
+// Config.Compressors). The offer is compressed only if it has a CompressionLevel; otherwise the
+// coding is blank (identity). The result is not acceptable (false) when the client has refused
+// every coding that the offer can provide; unless the offer is an IdentityProcessor, this
+// includes the codings that its processor might apply for itself.
+func (o Offer) NegotiateContentCoding(req *http.Request) (coding string, acceptable bool) {
+	if o.CompressionLevel != NoCompression {
+		return o.codings.negotiate(req, true)
+	}
+
+	_, acceptable = o.codings.negotiate(req, false)
+	if !acceptable && !o.IdentityProcessor {
+		// the processor may compress for itself
+		_, acceptable = o.codings.negotiate(req, true)
+	}
+	return "", acceptable
+}
+
+// contentCodings holds the content codings available to an offer (see Config.Compressors
+// and Config.ContentCodingPreference). Nil fields mean the package-level settings are used.
+type contentCodings struct {
+	compressors map[string]Compressor
+	preference  []string
+}
+
+// negotiate is as per NegotiateContentCoding, using these content codings.
+func (cc contentCodings) negotiate(req *http.Request, compress bool) (coding string, acceptable bool) {
+	values, present := req.Header[headername.AcceptEncoding]
+	if !present {
+		return "", true // any coding is acceptable, so no compression is needed
+	}
+
+	accepted := header.ParsePrecedenceValues(strings.Join(values, ","))
+
+	// identity is acceptable unless it has been excluded
+	bestQ, found := codingQuality(accepted, identity)
+	if !found {
+		bestQ = header.DefaultQuality
+	}
+
+	if compress {
+		for _, available := range cc.availableCodings() {
+			q, found := codingQuality(accepted, available)
+			// compression is preferred when the client rates it equal to identity
+			if found && q > 0 && (q > bestQ || (coding == "" && q == bestQ)) {
+				coding = available
+				bestQ = q
+			}
+		}
+	}
+
+	return coding, coding != "" || bestQ > 0
+}
+
+// codingQuality gets the quality of a content coding, which is explicitly listed
+// or is implied by the "*" wildcard.
+func codingQuality(accepted header.PrecedenceValues, coding string) (float64, bool) {
+	wildcard, hasWildcard := 0.0, false
+	for _, pv := range accepted {
+		switch {
+		case pv.Value == coding, coding == gzip && pv.Value == xgzip:
+			return pv.Quality, true
+		case pv.Value == "*":
+			wildcard, hasWildcard = pv.Quality, true
+		}
+	}
+	return wildcard, hasWildcard
+}
+
+// availableCodings lists the codings in Compressors in order of preference.
+func (cc contentCodings) availableCodings() []string {
+	compressors := cc.compressorsOrDefault()
+	preference := cc.preference
+	if preference == nil {
+		preference = ContentCodingPreference
+	}
+
+	codings := make([]string, 0, len(compressors))
+	for _, name := range preference {
+		if _, exists := compressors[name]; exists {
+			codings = append(codings, name)
+		}
+	}
+
+	var others []string
+	for name := range compressors {
+		if !slices.Contains(codings, name) {
+			others = append(others, name)
+		}
+	}
+	slices.Sort(others)
+
+	return append(codings, others...)
+}
+
+func (cc contentCodings) compressorsOrDefault() map[string]Compressor {
+	if cc.compressors == nil {
+		return Compressors
+	}
+	return cc.compressors
+}
+
+const (
+	identity = "identity"
+	gzip     = "gzip"
+	xgzip    = "x-gzip"
+	deflate  = "deflate"
+	br       = "br"
+	zstd     = "zstd"
+)
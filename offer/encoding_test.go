@@ -0,0 +1,108 @@
+package offer_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dpkg "github.com/rickb777/acceptable/data"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+func TestNegotiateContentCoding(t *testing.T) {
+	cases := []struct {
+		acceptEncoding []string // nil means absent
+		compress       bool
+		coding         string
+		acceptable     bool
+	}{
+		{acceptEncoding: nil, compress: true, coding: "", acceptable: true},
+		{acceptEncoding: []string{""}, compress: true, coding: "", acceptable: true},
+		{acceptEncoding: []string{"gzip"}, compress: true, coding: "gzip", acceptable: true},
+		{acceptEncoding: []string{"gzip"}, compress: false, coding: "", acceptable: true},
+		{acceptEncoding: []string{"x-gzip"}, compress: true, coding: "gzip", acceptable: true},
+		{acceptEncoding: []string{"GZIP"}, compress: true, coding: "gzip", acceptable: true},
+		{acceptEncoding: []string{"gzip;q=0"}, compress: true, coding: "", acceptable: true},
+		{acceptEncoding: []string{"gzip, deflate"}, compress: true, coding: "gzip", acceptable: true},
+		{acceptEncoding: []string{"gzip;q=0.5, deflate"}, compress: true, coding: "deflate", acceptable: true},
+		{acceptEncoding: []string{"gzip;q=0.5", "deflate"}, compress: true, coding: "deflate", acceptable: true},
+		{acceptEncoding: []string{"gzip;q=0.5, identity"}, compress: true, coding: "", acceptable: true},
+		{acceptEncoding: []string{"br, zstd"}, compress: true, coding: "", acceptable: true},
+		{acceptEncoding: []string{"*"}, compress: true, coding: "gzip", acceptable: true},
+		{acceptEncoding: []string{"*, gzip;q=0"}, compress: true, coding: "deflate", acceptable: true},
+		{acceptEncoding: []string{"identity;q=0"}, compress: true, coding: "", acceptable: false},
+		{acceptEncoding: []string{"gzip, identity;q=0"}, compress: false, coding: "", acceptable: false},
+		{acceptEncoding: []string{"gzip, identity;q=0"}, compress: true, coding: "gzip", acceptable: true},
+		{acceptEncoding: []string{"*;q=0"}, compress: true, coding: "", acceptable: false},
+		{acceptEncoding: []string{"deflate, *;q=0"}, compress: true, coding: "deflate", acceptable: true},
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if c.acceptEncoding != nil {
+			req.Header[AcceptEncoding] = c.acceptEncoding
+		}
+
+		coding, acceptable := offer.NegotiateContentCoding(req, c.compress)
+
+		expect.String(coding).I(c.acceptEncoding).ToBe(t, c.coding)
+		expect.Bool(acceptable).I(c.acceptEncoding).ToBe(t, c.acceptable)
+	}
+}
+
+func TestNegotiateContentCoding_using_additional_compressor(t *testing.T) {
+	offer.Compressors["br"] = func(w io.Writer, level int) (io.WriteCloser, error) {
+		return zlib.NewWriterLevel(w, level) // a stand-in for Brotli
+	}
+	defer delete(offer.Compressors, "br")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(AcceptEncoding, "gzip, deflate, br")
+
+	coding, acceptable := offer.NegotiateContentCoding(req, true)
+
+	expect.String(coding).ToBe(t, "br")
+	expect.Bool(acceptable).ToBeTrue(t)
+}
+
+func TestEncodingProcessor_should_compress_using_deflate(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(AcceptEncoding, "deflate, gzip;q=0.5")
+	rw := httptest.NewRecorder()
+
+	p := offer.TXTProcessor(offer.MidCompression)
+
+	err := p(rw, req, dpkg.Of("Hello world"), dpkg.Chosen{})
+
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Header().Get(ContentEncoding)).ToBe(t, "deflate")
+	expect.String(rw.Header().Get(Vary)).ToBe(t, "Accept-Encoding")
+
+	zr, err := zlib.NewReader(bytes.NewReader(rw.Body.Bytes()))
+	expect.Error(err).Not().ToHaveOccurred(t)
+	b, _ := io.ReadAll(zr)
+	expect.String(string(b)).ToBe(t, "Hello world\n")
+}
+
+func TestEncodingProcessor_should_not_repeat_vary(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(AcceptEncoding, "gzip")
+	rw := httptest.NewRecorder()
+
+	o := offer.Of(offer.TXTProcessor(offer.MidCompression), "text/plain").With("Hello world", "*")
+	m := o.BuildMatch(o.ContentType, "*")
+	m.Vary = []string{Accept, AcceptEncoding}
+
+	w := m.ApplyHeaders(rw)
+	err := m.Render(w, req, m.Data, dpkg.Chosen{})
+	w.Close()
+
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Header().Get(ContentEncoding)).ToBe(t, "gzip")
+	expect.String(rw.Header().Get(Vary)).ToBe(t, "Accept, Accept-Encoding")
+}
@@ -0,0 +1,161 @@
+package offer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	dpkg "github.com/rickb777/acceptable/data"
+	"github.com/rickb777/acceptable/headername"
+)
+
+// Event is a server-sent event. Data items of type Event or *Event are rendered with their ID,
+// name and retry interval; any other data item is rendered as an event containing only data.
+type Event struct {
+	// ID is the event identifier; clients send the last one they received in the
+	// Last-Event-ID header when they reconnect (optional)
+	ID string
+	// Name is the event type (optional; the default type is "message")
+	Name string
+	// Retry tells the client how long to wait before reconnecting (optional)
+	Retry time.Duration
+	// Data is the event payload, which is encoded using the EventEncoder
+	Data any
+}
+
+// EventEncoder encodes the payload of an event. The output may span several lines.
+type EventEncoder func(w io.Writer, data any) error
+
+// EventStream constructs a server-sent events Offer easily (see EventStreamProcessor).
+func EventStream(encoder ...EventEncoder) Offer {
+	return DefaultConfig().EventStream(encoder...)
+}
+
+// EventStreamProcessor creates a new processor for server-sent events (text/event-stream). This
+// renders each data item in a sequence as an event (see Event), and flushes the response after
+// each one. Each event is written as soon as it is available (see data.Streamer). Nil items are
+// skipped. The sequence stops when the request context is cancelled.
+//
+// The event payloads are encoded using the optional encoder. By default, strings and byte slices
+// are written unaltered and other values are encoded as JSON using NewJSONEncoder.
+//
+// When a client reconnects, it sends the ID of the last event it received. This is passed to
+// the data as Chosen.LastEventID, so that a supplier (e.g. see data.Sequence) can resume from
+// the following event.
+//
+// Event streams are not compressed.
+func EventStreamProcessor(encoder ...EventEncoder) Processor {
+	return eventStreamProcessor(defaultEventEncoder(defaultJSONEncoder), encoder...)
+}
+
+// LastEventID gets the ID of the last event received by a reconnecting client, if any.
+func LastEventID(req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+	return req.Header.Get(headername.LastEventID)
+}
+
+func defaultEventEncoder(newEncoder func(w io.Writer) JSONEncoder) EventEncoder {
+	return func(w io.Writer, data any) error {
+		switch v := data.(type) {
+		case string:
+			_, err := io.WriteString(w, v)
+			return err
+		case []byte:
+			_, err := w.Write(v)
+			return err
+		}
+		return newEncoder(w).Encode(data)
+	}
+}
+
+func eventStreamProcessor(defaultEncoder EventEncoder, encoder ...EventEncoder) Processor {
+	encode := defaultEncoder
+	if len(encoder) > 0 && encoder[0] != nil {
+		encode = encoder[0]
+	}
+
+	return func(w io.Writer, req *http.Request, data dpkg.Data, chosen dpkg.Chosen) (err error) {
+		ctx := chosen.Context()
+		chosen.LastEventID = LastEventID(req)
+
+		content := data.Content(chosen)
+		defer content.Close()
+
+		buf := &bytes.Buffer{}
+
+		next := dpkg.Stream(content)
+		for {
+			if err = ctx.Err(); err != nil {
+				return err // the client has gone away
+			}
+
+			item, ok, err := next()
+			if err != nil || !ok {
+				return err
+			}
+
+			if item == nil {
+				continue
+			}
+
+			buf.Reset()
+			if err = writeEvent(buf, item, encode); err != nil {
+				return err
+			}
+
+			if _, err = w.Write(buf.Bytes()); err != nil {
+				return err
+			}
+
+			flush(w)
+		}
+	}
+}
+
+// lineBreaks removes characters that cannot appear in event fields.
+var lineBreaks = strings.NewReplacer("\r", "", "\n", "", "\x00", "")
+
+func writeEvent(buf *bytes.Buffer, item any, encode EventEncoder) error {
+	var ev Event
+	switch v := item.(type) {
+	case Event:
+		ev = v
+	case *Event:
+		ev = *v
+	default:
+		ev = Event{Data: item}
+	}
+
+	if ev.ID != "" {
+		buf.WriteString("id: " + lineBreaks.Replace(ev.ID) + "\n")
+	}
+	if ev.Name != "" {
+		buf.WriteString("event: " + lineBreaks.Replace(ev.Name) + "\n")
+	}
+	if ev.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	if ev.Data != nil {
+		payload := &bytes.Buffer{}
+		if err := encode(payload, ev.Data); err != nil {
+			return err
+		}
+
+		// CR and CRLF are line terminators too, so they must not split the data lines
+		text := strings.ReplaceAll(payload.String(), "\r\n", "\n")
+		text = strings.ReplaceAll(text, "\r", "\n")
+		text = strings.TrimSuffix(text, "\n")
+		for _, line := range strings.Split(text, "\n") {
+			buf.WriteString("data: " + line + "\n")
+		}
+	}
+
+	buf.WriteByte('\n')
+	return nil
+}
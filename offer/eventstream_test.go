@@ -0,0 +1,130 @@
+package offer_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	dpkg "github.com/rickb777/acceptable/data"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+func TestEventStreamShouldWriteEvents(t *testing.T) {
+	// Given ...
+	req := &http.Request{}
+	rw := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+
+	items := []any{
+		offer.Event{ID: "1", Name: "update", Retry: 3 * time.Second, Data: User{Name: "Ann"}},
+		&offer.Event{ID: "2", Data: "line one\nline two"},
+		User{Name: "Joe"},
+		offer.Event{Name: "bad\nname"},
+		"x\revent: admin",
+	}
+
+	o := offer.EventStream().With(dpkg.Seq(slices.Values(items)), "*")
+	m := o.BuildMatch(o.ContentType, "*")
+
+	// When ...
+	w := m.ApplyHeaders(rw)
+	err := m.Render(w, req, m.Data, dpkg.Chosen{})
+	w.Close()
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Header().Get(ContentType)).ToBe(t, "text/event-stream;charset=utf-8")
+	expect.String(rw.Body.String()).ToBe(t,
+		"id: 1\nevent: update\nretry: 3000\ndata: {\"Name\":\"Ann\"}\n\n"+
+			"id: 2\ndata: line one\ndata: line two\n\n"+
+			"data: {\"Name\":\"Joe\"}\n\n"+
+			"event: badname\n\n"+
+			"data: x\ndata: event: admin\n\n")
+	expect.Slice(rw.flushed).ToHaveLength(t, 5)
+}
+
+func TestEventStreamShouldUseEncoder(t *testing.T) {
+	// Given ...
+	req := &http.Request{}
+	rw := httptest.NewRecorder()
+
+	p := offer.EventStreamProcessor(func(w io.Writer, data any) error {
+		_, err := fmt.Fprintf(w, "<%v>", data)
+		return err
+	})
+
+	// When ...
+	err := p(rw, req, dpkg.Of(42), dpkg.Chosen{})
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Body.String()).ToBe(t, "data: <42>\n\n")
+}
+
+func TestEventStreamShouldStopWhenContextIsCancelled(t *testing.T) {
+	// Given ...
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/", nil)
+	rw := httptest.NewRecorder()
+
+	events := make(chan offer.Event)
+	go func() {
+		events <- offer.Event{ID: "1", Data: "a"}
+		cancel()
+	}()
+
+	p := offer.EventStreamProcessor()
+
+	// When ...
+	err := p(rw, req, dpkg.Chan(events), dpkg.Chosen{Request: req})
+
+	// Then ...
+	expect.Error(err).ToWrap(t, context.Canceled)
+	expect.String(rw.Body.String()).ToBe(t, "id: 1\ndata: a\n\n")
+}
+
+func TestEventStreamShouldResumeAfterLastEventID(t *testing.T) {
+	// Given ...
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(LastEventID, "1")
+	rw := httptest.NewRecorder()
+
+	// the supplier resumes from the event after the last one the client received
+	var next int
+	d := dpkg.Sequence(func(chosen dpkg.Chosen) (any, error) {
+		if next == 0 {
+			last, _ := strconv.Atoi(chosen.LastEventID)
+			next = last + 1
+		}
+		if next > 3 {
+			return nil, nil
+		}
+		ev := offer.Event{ID: strconv.Itoa(next), Data: next * 10}
+		next++
+		return ev, nil
+	})
+
+	p := offer.EventStreamProcessor()
+
+	// When ...
+	err := p(rw, req, d, dpkg.Chosen{Request: req})
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Body.String()).ToBe(t, "id: 2\ndata: 20\n\nid: 3\ndata: 30\n\n")
+}
+
+func TestLastEventID(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(LastEventID, "42")
+
+	expect.String(offer.LastEventID(req)).ToBe(t, "42")
+	expect.String(offer.LastEventID(nil)).ToBe(t, "")
+}
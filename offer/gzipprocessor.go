@@ -2,11 +2,12 @@ package offer
 
 import (
 	gzippkg "compress/gzip"
+	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	dpkg "github.com/rickb777/acceptable/data"
-	"github.com/rickb777/acceptable/header"
 	"github.com/rickb777/acceptable/headername"
 )
 
@@ -15,34 +16,95 @@ const (
 	MidCompression = 5
 )
 
-// GZIPLevel sets the compression strength when gzip is applied to a response entity.
-// This is in the range 1 to 9 inclusive (see gzip.NewWriterLevel). High values should
-// be avoided because the cpu cost is high but the benefit may not be sufficient.
+// GZIPLevel sets the compression strength when a content coding such as gzip is applied to a
+// response entity. This is in the range 1 to 9 inclusive (see gzip.NewWriterLevel). High values
+// should be avoided because the cpu cost is high but the benefit may not be sufficient.
+// An invalid level causes a panic when offers and processors are constructed.
+//
+// This is the default for DefaultConfig; use Config for independent settings.
 var GZIPLevel = MidCompression
 
-func GZIPProcessor(level int, mainProc Processor) Processor {
+// EncodingProcessor wraps a processor so that its output is compressed using the content
+// coding chosen by NegotiateContentCoding, if any. This is for processors that are used
+// directly. When a processor is used via an Offer, Offer.CompressionLevel is preferred
+// because it allows the content coding to be negotiated with the media type and language.
+//
+// When w is a ResponseWriter (see Match.ApplyHeaders), the compression is inserted into its
+// pipeline so that it works alongside any charset transcoding; otherwise a new ResponseWriter
+// is used, so that flushing the response also flushes the compressor. Compression is not
+// applied if the response already has a Content-Encoding.
+//
+// This panics if the level is not valid for compress/gzip (see GZIPLevel).
+func EncodingProcessor(level int, mainProc Processor) Processor {
+	return Config{}.EncodingProcessor(level, mainProc)
+}
+
+// EncodingProcessor wraps a processor so that its output is compressed using one of this
+// configuration's content codings (see EncodingProcessor).
+func (c Config) EncodingProcessor(level int, mainProc Processor) Processor {
+	checkCompressionLevel(level)
+	codings := c.codings()
 	return func(w io.Writer, req *http.Request, data dpkg.Data, chosen dpkg.Chosen) (err error) {
-		if level == gzippkg.NoCompression {
+		if level == NoCompression {
 			return mainProc(w, req, data, chosen)
 		}
 
-		acceptEncoding := header.ParsePrecedenceValues(req.Header.Get(headername.AcceptEncoding))
-		if !acceptEncoding.Contains(gzip) {
+		rw, isRW := w.(http.ResponseWriter)
+		if !isRW || rw.Header().Get(headername.ContentEncoding) != "" {
 			return mainProc(w, req, data, chosen)
 		}
 
-		rw := w.(http.ResponseWriter)
-		rw.Header().Add(headername.ContentEncoding, gzip)
-		vary := rw.Header().Get(headername.Vary)
-		rw.Header().Set(headername.Vary, joinWithComma(vary, headername.AcceptEncoding))
+		coding, _ := codings.negotiate(req, true)
+		if coding == "" {
+			return mainProc(w, req, data, chosen)
+		}
+
+		pw, isPipeline := w.(*ResponseWriter)
+		if !isPipeline {
+			// the pipeline flushes the compressor whenever the response is flushed
+			pw = NewResponseWriter(rw, w, nil)
+			defer func() {
+				if e := pw.Close(); err == nil {
+					err = e
+				}
+			}()
+		}
+
+		// the compressor goes beneath any charset transcoding
+		if err = pw.setContentEncoding(codings.compressorsOrDefault(), coding, level); err != nil {
+			return err
+		}
+		addVary(rw, headername.AcceptEncoding)
+		return mainProc(pw, req, data, chosen)
+	}
+}
+
+// checkCompressionLevel panics if level is not a compression level in the range used by
+// compress/gzip, i.e. -2 (HuffmanOnly) to 9 (BestCompression). This is a misconfiguration, so
+// it is checked when offers and processors are constructed rather than for each response.
+func checkCompressionLevel(level int) {
+	if level < gzippkg.HuffmanOnly || level > gzippkg.BestCompression {
+		panic(fmt.Sprintf("compression level %d is not in the range %d to %d (see offer.GZIPLevel)",
+			level, gzippkg.HuffmanOnly, gzippkg.BestCompression))
+	}
+}
 
-		gw, err := gzippkg.NewWriterLevel(w, level)
-		if err != nil {
-			panic(err.Error() + " (see offer.GZIPLevel)") // misconfiguration
+// GZIPProcessor wraps a processor so that its output is compressed.
+//
+// Deprecated: use EncodingProcessor, which also supports other content codings.
+func GZIPProcessor(level int, mainProc Processor) Processor {
+	return EncodingProcessor(level, mainProc)
+}
+
+// addVary adds a header name to the Vary header, unless it is already listed.
+func addVary(rw http.ResponseWriter, name string) {
+	vary := rw.Header().Get(headername.Vary)
+	for _, v := range strings.Split(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), name) {
+			return
 		}
-		defer gw.Close()
-		return mainProc(gw, req, data, chosen)
 	}
+	rw.Header().Set(headername.Vary, joinWithComma(vary, name))
 }
 
 func joinWithComma(a, b string) string {
@@ -51,5 +113,3 @@ func joinWithComma(a, b string) string {
 	}
 	return a + ", " + b
 }
-
-const gzip = "gzip"
@@ -5,15 +5,14 @@ import (
 	"io"
 	"net/http"
 
-	"github.com/rickb777/acceptable/contenttype"
 	dpkg "github.com/rickb777/acceptable/data"
 	"github.com/rickb777/acceptable/internal"
 )
 
 // JSON constructs a JSON Offer easily.
-// The response will use gzip compression (see [GZIPLevel]) when the client requests it.
+// The response will be compressed (see [GZIPLevel]) when the client accepts a content coding such as gzip.
 func JSON(indent ...string) Offer {
-	return Of(JSONProcessor(GZIPLevel, indent...), contenttype.ApplicationJSON)
+	return DefaultConfig().JSON(indent...)
 }
 
 // JSONProcessor creates a new processor for JSON with a specified indentation. This converts
@@ -24,10 +23,34 @@ func JSON(indent ...string) Offer {
 //
 // The optional indent argument is a string usually of zero or more space characters.
 func JSONProcessor(gzipLevel int, indent ...string) Processor {
-	return GZIPProcessor(gzipLevel, jsonProcessor(indent...))
+	return EncodingProcessor(gzipLevel, jsonProcessor(defaultJSONEncoder, indent...))
 }
 
-func jsonProcessor(indent ...string) Processor {
+// JSONSeq constructs an Offer for JSON text sequences (application/json-seq) easily (see JSONSeqProcessor).
+// The response will be compressed (see [GZIPLevel]) when the client accepts a content coding such as gzip.
+func JSONSeq() Offer {
+	return DefaultConfig().JSONSeq()
+}
+
+// JSONSeqProcessor creates a new processor for JSON text sequences (RFC-7464). Each data item in
+// a sequence is written as a JSON text preceded by the record separator character (RS, 0x1E)
+// and followed by a newline, so that a truncated stream can be detected and the remaining items
+// recovered. The response is flushed after each item. Nil items are skipped.
+func JSONSeqProcessor(gzipLevel int) Processor {
+	return EncodingProcessor(gzipLevel, jsonSeqProcessor(defaultJSONEncoder))
+}
+
+// recordSeparator precedes each JSON text in a sequence (RFC-7464).
+var recordSeparator = []byte{0x1E}
+
+func jsonSeqProcessor(newEncoder func(w io.Writer) JSONEncoder) Processor {
+	return jsonTextsProcessor(newEncoder, recordSeparator, 1)
+}
+
+// defaultJSONEncoder defers to NewJSONEncoder at the time of use.
+func defaultJSONEncoder(w io.Writer) JSONEncoder { return NewJSONEncoder(w) }
+
+func jsonProcessor(newEncoder func(w io.Writer) JSONEncoder, indent ...string) Processor {
 	in := ""
 	if len(indent) > 0 {
 		in = indent[0]
@@ -36,9 +59,12 @@ func jsonProcessor(indent ...string) Processor {
 	return func(w io.Writer, _ *http.Request, data dpkg.Data, chosen dpkg.Chosen) (err error) {
 		p := internal.EnsureNewline(w)
 
-		enc := NewJSONEncoder(p)
+		enc := newEncoder(p)
+
+		content := data.Content(chosen)
+		defer content.Close()
 
-		item, more, err := data.Content(chosen)
+		item, more, err := content.Next()
 		if err != nil {
 			return err
 		}
@@ -68,7 +94,7 @@ func jsonProcessor(indent ...string) Processor {
 			p.Write(comma)
 			p.Write(newline)
 
-			item, stillMore, err = data.Content(chosen)
+			item, stillMore, err = content.Next()
 			if err != nil {
 				return err
 			}
@@ -88,6 +114,50 @@ func jsonProcessor(indent ...string) Processor {
 	}
 }
 
+// jsonTextsProcessor writes each data item as a separate JSON text, preceded by the separator
+// and followed by a newline, flushing the response after every batch of items. Nil items are
+// skipped.
+func jsonTextsProcessor(newEncoder func(w io.Writer) JSONEncoder, separator []byte, batch int) Processor {
+	return func(w io.Writer, _ *http.Request, data dpkg.Data, chosen dpkg.Chosen) (err error) {
+		p := internal.EnsureNewline(w)
+
+		enc := newEncoder(p)
+		enc.SetIndent("", "")
+
+		content := data.Content(chosen)
+		defer content.Close()
+
+		next := dpkg.Stream(content)
+		for n := 0; ; {
+			item, ok, err := next()
+			if err != nil || !ok {
+				return err
+			}
+
+			if item == nil {
+				continue
+			}
+
+			if _, err = p.Write(separator); err != nil {
+				return err
+			}
+
+			if err = enc.Encode(item); err != nil {
+				return err
+			}
+
+			if err = p.FinalNewline(); err != nil {
+				return err
+			}
+
+			n++
+			if n%batch == 0 {
+				flush(w)
+			}
+		}
+	}
+}
+
 // JSONEncoder summarises the key methods of the standard JSON encoder.
 type JSONEncoder interface {
 	SetIndent(string, string)
@@ -95,4 +165,5 @@ type JSONEncoder interface {
 }
 
 // NewJSONEncoder is a pluggable JSON encoder, initialised with the standard library implementation.
+// Config.NewJSONEncoder can be used instead for independent settings.
 var NewJSONEncoder = func(w io.Writer) JSONEncoder { return json.NewEncoder(w) }
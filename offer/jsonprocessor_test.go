@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 
 	dpkg "github.com/rickb777/acceptable/data"
@@ -130,3 +131,31 @@ type User struct {
 func (u *User) MarshalJSON() ([]byte, error) {
 	return nil, errors.New("oops")
 }
+
+func TestJSONSeqShouldWriteResponseBody_sequence(t *testing.T) {
+	req := &http.Request{}
+	rw := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+
+	o := offer.JSONSeq().With(dpkg.Seq(slices.Values([]any{User{Name: "Ann"}, nil, "two"})), "*")
+	m := o.BuildMatch(o.ContentType, "*")
+
+	w := m.ApplyHeaders(rw)
+	err := m.Render(w, req, m.Data, dpkg.Chosen{})
+	w.Close()
+
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Header().Get(ContentType)).ToBe(t, "application/json-seq")
+	expect.String(rw.Body.String()).ToBe(t, "\x1e{\"Name\":\"Ann\"}\n\x1e\"two\"\n")
+	expect.Slice(rw.flushed).ToHaveLength(t, 2)
+}
+
+func TestJSONSeqShouldWriteResponseBody_single_value(t *testing.T) {
+	req := &http.Request{}
+	rw := httptest.NewRecorder()
+
+	p := offer.JSONSeqProcessor(0)
+	err := p(rw, req, dpkg.Of(42), dpkg.Chosen{})
+
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Body.String()).ToBe(t, "\x1e42\n")
+}
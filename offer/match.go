@@ -11,17 +11,35 @@ import (
 	"github.com/rickb777/acceptable/headername"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/language"
 )
 
 // Match holds the best-matched offer after content negotiation and is used for response rendering.
 type Match struct {
 	header.ContentType
-	Language           string
-	Charset            string
+	Language string
+	// Confidence is how well Language matched the Accept-Language header. It is set only
+	// when the acceptable.BestFit language matching is in use; otherwise it is language.No.
+	Confidence language.Confidence
+	// Quality is the effective quality of the match, i.e. the quality of the matching media
+	// range in the Accept header multiplied by the offer's source quality.
+	Quality float64
+	Charset string
+	// ContentEncoding is the content coding chosen via the Accept-Encoding header, or blank
+	// for the identity coding (i.e. no compression).
+	ContentEncoding string
+	// IdentityProcessor is copied from the offer (see Offer.IdentityProcessor). When it is
+	// false, the processor may apply a content coding for itself.
+	IdentityProcessor bool
+	// RawContent is copied from the offer (see Offer.RawContent).
+	RawContent         bool
 	Vary               []string
 	Data               dpkg.Data
 	Render             Processor
 	StatusCodeOverride int
+
+	compressionLevel int
+	codings          contentCodings
 }
 
 //-------------------------------------------------------------------------------------------------
@@ -29,11 +47,23 @@ type Match struct {
 // ApplyHeaders sets response headers so that the user agent is notified of the content
 // negotiation decisions made. Four headers may be set, depending on context.
 //
-//   - Content-Type is always set.
+//   - Content-Type is always set, including any media type parameters of the matched offer.
 //   - Content-Language is set when a language was selected.
-//   - Content-Encoding is set when the character set is being transcoded
-//   - Vary is set to list the accept headers that led to the three decisions above.
-func (m Match) ApplyHeaders(rw http.ResponseWriter) io.Writer {
+//   - Content-Encoding is set when the response is being compressed.
+//   - Vary is set to list the accept headers that led to the decisions above.
+//
+// The writer returned will transcode and/or compress the response as required. It must be
+// closed after the response has been written, otherwise the end of the transcoded and/or
+// compressed response body will be lost. Note that this is a breaking change: previously,
+// ApplyHeaders returned an io.Writer that was not closed, so existing callers need to be
+// changed to close the returned writer.
+func (m Match) ApplyHeaders(rw http.ResponseWriter) *ResponseWriter {
+	return m.ApplyHeadersTo(rw, rw)
+}
+
+// ApplyHeadersTo is the same as ApplyHeaders except that the response body is written to
+// sink instead of rw. This allows the encoded response to be buffered, for example.
+func (m Match) ApplyHeadersTo(rw http.ResponseWriter, sink io.Writer) *ResponseWriter {
 	charset := "utf-8"
 
 	var enc encoding.Encoding
@@ -50,9 +80,9 @@ func (m Match) ApplyHeaders(rw http.ResponseWriter) io.Writer {
 	}
 
 	if m.Type() == "text" {
-		rw.Header().Set(headername.ContentType, fmt.Sprintf("%s;charset=%s", m.MediaType, charset))
+		rw.Header().Set(headername.ContentType, fmt.Sprintf("%s;charset=%s", m.ContentType, charset))
 	} else {
-		rw.Header().Set(headername.ContentType, m.MediaType)
+		rw.Header().Set(headername.ContentType, m.ContentType.String())
 	}
 
 	if m.IsTextual() && m.Language != "" && m.Language != "*" {
@@ -63,11 +93,15 @@ func (m Match) ApplyHeaders(rw http.ResponseWriter) io.Writer {
 		rw.Header().Set(headername.Vary, strings.Join(m.Vary, ", "))
 	}
 
-	if enc != nil {
-		return enc.NewEncoder().Writer(rw)
+	w := NewResponseWriter(rw, sink, enc)
+
+	if m.ContentEncoding != "" {
+		if err := w.setContentEncoding(m.codings.compressorsOrDefault(), m.ContentEncoding, m.compressionLevel); err != nil {
+			panic(err.Error() + " (see Config.Compressors and offer.CompressionLevel)") // misconfiguration
+		}
 	}
 
-	return rw
+	return w
 }
 
 func (m Match) String() string {
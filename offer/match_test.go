@@ -83,6 +83,34 @@ func TestApplyHeaders(t *testing.T) {
 			},
 			utf8: true,
 		},
+		{
+			str: "application/json; charset=utf-8; lang=fr vary=[Accept]; no data; no renderer",
+			m: offer.Match{
+				ContentType: header.ContentType{MediaType: "application/json", Params: []header.KV{{Key: "version", Value: "2"}}},
+				Language:    "fr",
+				Charset:     "utf-8",
+				Vary:        []string{Accept},
+			},
+			hdrs: map[string]string{
+				ContentType:     "application/json;version=2",
+				ContentLanguage: "fr",
+				Vary:            Accept,
+			},
+			utf8: true,
+		},
+		{
+			str: "text/html; charset=utf-8; lang=fr vary=[]; no data; no renderer",
+			m: offer.Match{
+				ContentType: header.ContentType{MediaType: "text/html", Params: []header.KV{{Key: "level", Value: "1"}}},
+				Language:    "fr",
+				Charset:     "utf-8",
+			},
+			hdrs: map[string]string{
+				ContentType:     "text/html;level=1;charset=utf-8",
+				ContentLanguage: "fr",
+			},
+			utf8: true,
+		},
 		{
 			str: "application/octet-stream; charset=utf-8; lang=fr vary=[]; no data; no renderer",
 			m: offer.Match{
@@ -108,7 +136,7 @@ func TestApplyHeaders(t *testing.T) {
 		info := fmt.Sprintf("%d:%s", i, c.m)
 		expect.String(c.m.String()).I(info).ToBe(t, c.str)
 		if c.utf8 {
-			expect.Value(w).I(info).ToBe(t, rec)
+			expect.Value(w.Unwrap()).I(info).ToBe(t, rec)
 		}
 		expect.Map(rec.HeaderMap).I(info).ToHaveLength(t, len(c.hdrs))
 		for h, v := range c.hdrs {
@@ -0,0 +1,41 @@
+package offer
+
+import (
+	"io"
+)
+
+// MsgPack constructs a MessagePack Offer easily. NewMsgPackEncoder must have been set.
+// The response will be compressed (see [GZIPLevel]) when the client accepts a content coding such as gzip.
+func MsgPack() Offer {
+	return DefaultConfig().MsgPack()
+}
+
+// MsgPackProcessor creates a new processor for MessagePack. This converts a data item into
+// MessagePack using NewMsgPackEncoder. A sequence of data items is written as concatenated
+// MessagePack values. A single nil value is encoded as MessagePack nil, but nil items in a
+// longer sequence are skipped.
+func MsgPackProcessor(gzipLevel int) Processor {
+	mustHaveEncoder(NewMsgPackEncoder != nil, "NewMsgPackEncoder")
+	return EncodingProcessor(gzipLevel, msgPackProcessor(defaultMsgPackEncoder))
+}
+
+// defaultMsgPackEncoder defers to NewMsgPackEncoder at the time of use.
+func defaultMsgPackEncoder(w io.Writer) MsgPackEncoder { return NewMsgPackEncoder(w) }
+
+func msgPackProcessor(newEncoder func(w io.Writer) MsgPackEncoder) Processor {
+	return concatenatedProcessor(func(w io.Writer) func(any) error { return newEncoder(w).Encode })
+}
+
+// MsgPackEncoder summarises the key method of the MessagePack encoder.
+type MsgPackEncoder interface {
+	Encode(any) error
+}
+
+// NewMsgPackEncoder is a pluggable MessagePack encoder. It is nil initially, so that this package
+// does not depend on any particular MessagePack library; it must be set before MessagePack offers
+// are constructed, e.g. using github.com/vmihailenco/msgpack/v5
+//
+//	offer.NewMsgPackEncoder = func(w io.Writer) offer.MsgPackEncoder { return msgpack.NewEncoder(w) }
+//
+// Config.NewMsgPackEncoder can be used instead for independent settings.
+var NewMsgPackEncoder func(w io.Writer) MsgPackEncoder
@@ -0,0 +1,40 @@
+package offer_test
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+// msgPackEncoder writes real MessagePack but only for the few small values used in these tests.
+type msgPackEncoder struct {
+	w io.Writer
+}
+
+func (e msgPackEncoder) Encode(v any) error {
+	var b []byte
+	switch x := v.(type) {
+	case nil:
+		b = []byte{0xc0} // nil
+	case int:
+		b = []byte{byte(x)} // positive fixint 0..127
+	case string:
+		b = append([]byte{0xa0 | byte(len(x))}, x...) // fixstr up to 31 bytes
+	default:
+		return fmt.Errorf("unsupported %T", v)
+	}
+	_, err := e.w.Write(b)
+	return err
+}
+
+func newMsgPackEncoder(w io.Writer) offer.MsgPackEncoder { return msgPackEncoder{w: w} }
+
+func TestMsgPack_should_panic_without_an_encoder(t *testing.T) {
+	defer func() {
+		expect.Any(recover()).Not().ToBeNil(t)
+	}()
+	offer.MsgPack()
+}
@@ -0,0 +1,38 @@
+package offer
+
+import (
+	"io"
+	"net/http"
+)
+
+// NDJSON constructs a newline-delimited JSON (JSON Lines) Offer easily (see NDJSONProcessor).
+// The response will be compressed (see [GZIPLevel]) when the client accepts a content coding such as gzip.
+func NDJSON(batch ...int) Offer {
+	return DefaultConfig().NDJSON(batch...)
+}
+
+// NDJSONProcessor creates a new processor for newline-delimited JSON, also known as JSON Lines
+// (application/x-ndjson). This converts each data item in a sequence into JSON on a line of its
+// own, so that streaming consumers can process the items as they arrive. Nil items are skipped.
+//
+// The response is flushed after each item is written, or after every batch of items if the
+// optional batch argument is greater than one. Items are written as soon as they are available
+// (see data.Streamer).
+func NDJSONProcessor(gzipLevel int, batch ...int) Processor {
+	return EncodingProcessor(gzipLevel, ndjsonProcessor(defaultJSONEncoder, batch...))
+}
+
+func ndjsonProcessor(newEncoder func(w io.Writer) JSONEncoder, batch ...int) Processor {
+	size := 1
+	if len(batch) > 0 && batch[0] > 1 {
+		size = batch[0]
+	}
+	return jsonTextsProcessor(newEncoder, nil, size)
+}
+
+// flush sends buffered output to the client, if w supports http.Flusher.
+func flush(w io.Writer) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,119 @@
+package offer_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rickb777/acceptable/contenttype"
+	dpkg "github.com/rickb777/acceptable/data"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+// flushCounter records the body written before each flush.
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushed []string
+}
+
+func (f *flushCounter) Flush() {
+	f.flushed = append(f.flushed, f.Body.String())
+}
+
+func TestNDJSONShouldWriteResponseBody_sequence(t *testing.T) {
+	cases := []struct {
+		batch   []int
+		flushed []string
+	}{
+		{
+			batch:   nil,
+			flushed: []string{"{\"Name\":\"Ann\"}\n", "{\"Name\":\"Ann\"}\n{\"Name\":\"Joe\"}\n", "{\"Name\":\"Ann\"}\n{\"Name\":\"Joe\"}\n{\"Name\":\"Jane\"}\n"},
+		},
+		{
+			batch:   []int{2},
+			flushed: []string{"{\"Name\":\"Ann\"}\n{\"Name\":\"Joe\"}\n"},
+		},
+	}
+
+	for i, c := range cases {
+		// Given ...
+		req := &http.Request{}
+		rw := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+
+		o := offer.NDJSON(c.batch...).With(dpkg.Seq(slices.Values([]User{{"Ann"}, {"Joe"}, {"Jane"}})), "*")
+		m := o.BuildMatch(o.ContentType, "*")
+
+		// When ...
+		w := m.ApplyHeaders(rw)
+		err := m.Render(w, req, m.Data, dpkg.Chosen{})
+		w.Close()
+
+		// Then ...
+		expect.Error(err).I(i).Not().ToHaveOccurred(t)
+		expect.String(rw.Header().Get(ContentType)).I(i).ToBe(t, contenttype.ApplicationNDJSON)
+		expect.String(rw.Body.String()).I(i).ToBe(t, "{\"Name\":\"Ann\"}\n{\"Name\":\"Joe\"}\n{\"Name\":\"Jane\"}\n")
+		expect.Slice(rw.flushed).I(i).ToBe(t, c.flushed...)
+	}
+}
+
+func TestNDJSONShouldWriteResponseBody_single_value(t *testing.T) {
+	cases := []struct {
+		data     dpkg.Data
+		expected string
+	}{
+		{data: dpkg.Of(User{Name: "Ann"}), expected: "{\"Name\":\"Ann\"}\n"},
+		{data: dpkg.Seq(slices.Values([]User{})), expected: ""},
+	}
+
+	for i, c := range cases {
+		// Given ...
+		req := &http.Request{}
+		rw := httptest.NewRecorder()
+
+		p := offer.NDJSONProcessor(0)
+
+		// When ...
+		err := p(rw, req, c.data, dpkg.Chosen{})
+
+		// Then ...
+		expect.Error(err).I(i).Not().ToHaveOccurred(t)
+		expect.String(rw.Body.String()).I(i).ToBe(t, c.expected)
+	}
+}
+
+func TestNDJSONProcessorShouldFlushCompressedItems(t *testing.T) {
+	// Given ...
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(AcceptEncoding, "gzip")
+	rw := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+
+	p := offer.NDJSONProcessor(offer.MidCompression)
+
+	// When ...
+	err := p(rw, req, dpkg.Seq(slices.Values([]User{{"Ann"}, {"Joe"}})), dpkg.Chosen{})
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Header().Get(ContentEncoding)).ToBe(t, "gzip")
+	expect.Slice(rw.flushed).ToHaveLength(t, 2)
+
+	// each flush has sent the compressed item so far
+	zr, err := gzip.NewReader(strings.NewReader(rw.flushed[0]))
+	expect.Error(err).Not().ToHaveOccurred(t)
+	first := make([]byte, 15)
+	_, err = io.ReadFull(zr, first)
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(string(first)).ToBe(t, "{\"Name\":\"Ann\"}\n")
+
+	zr, err = gzip.NewReader(rw.Body)
+	expect.Error(err).Not().ToHaveOccurred(t)
+	all, err := io.ReadAll(zr)
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(string(all)).ToBe(t, "{\"Name\":\"Ann\"}\n{\"Name\":\"Joe\"}\n")
+}
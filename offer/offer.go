@@ -39,6 +39,41 @@ type Offer struct {
 	// The value will be the required status code (e.g. 400 for Bad Request, or 406 for Not
 	// Acceptable).
 	Handle406As int
+
+	// SourceQuality is the server's own assessment of the quality of this offer, in the range
+	// 0 (exclusive) to 1 (inclusive). It is known as "qs" in Apache's negotiation algorithm.
+	// During content negotiation, it is multiplied by the quality of the matching media range
+	// in the Accept header and the offers are ranked accordingly. This allows lossy
+	// representations to lose out to better ones when the client rates them equally.
+	// The default is 1; zero is treated as the default, as in an Offer literal.
+	SourceQuality float64
+
+	// CompressionLevel enables the response to be compressed using a content coding chosen
+	// via the Accept-Encoding header (see Compressors). It is the compression strength, in the
+	// range used by compress/gzip, e.g. 1 to 9 inclusive; compressors for other content codings
+	// adapt it as needed. The default is NoCompression, in which case the response will always
+	// use the identity coding (unless the processor compresses for itself).
+	CompressionLevel int
+
+	// IdentityProcessor is true when the processor never compresses the response for itself,
+	// so that a client that refuses the identity coding cannot be served without a
+	// CompressionLevel. This is so for the offers constructed by this package, such as JSON
+	// and TextPlain. Offers constructed using Of leave it false because their processor may
+	// compress for itself, e.g. if it was wrapped using EncodingProcessor.
+	IdentityProcessor bool
+
+	// RawContent is true when the processor writes the data unaltered, as BinaryProcessor does.
+	// Only then can data that is an io.ReadSeeker be served directly for byte range requests
+	// (see acceptable.RespondWith.AcceptRanges); otherwise, the processor's output is used.
+	// This is so for ImageJPEG and ImagePNG.
+	RawContent bool
+
+	// codings holds the content codings available for compression (see Config.Compressors).
+	codings contentCodings
+
+	// Charsets lists the character sets that the offer supports (see WithCharsets).
+	// If it is empty, utf-8 and any charset that can be transcoded are supported.
+	Charsets []string
 }
 
 // Of constructs an Offer easily, given a content type.
@@ -51,20 +86,36 @@ type Offer struct {
 // The correct behaviour is a 406 when no match can be made.
 func Of(processor Processor, contentType string) Offer {
 	return Offer{
-		ContentType: header.ParseContentType(contentType).WithDefault(),
-		processor:   processor,
-		Langs:       []string{"*"},
-		data:        make(map[string]dpkg.Data),
+		ContentType:   header.ParseContentType(contentType).WithDefault(),
+		processor:     processor,
+		Langs:         []string{"*"},
+		data:          make(map[string]dpkg.Data),
+		SourceQuality: header.DefaultQuality,
 	}
 }
 
+// of constructs an Offer for one of this package's processors, which do not compress
+// for themselves.
+func of(processor Processor, contentType string) Offer {
+	o := Of(processor, contentType)
+	o.IdentityProcessor = true
+	return o
+}
+
 // clone makes a defensive copy of the original offer.
 func (o Offer) clone() Offer {
 	c := Offer{
-		ContentType: o.ContentType,
-		processor:   o.processor,
-		Langs:       make([]string, len(o.Langs)),
-		data:        make(map[string]dpkg.Data),
+		ContentType:       o.ContentType,
+		processor:         o.processor,
+		Langs:             make([]string, len(o.Langs)),
+		data:              make(map[string]dpkg.Data),
+		Handle406As:       o.Handle406As,
+		SourceQuality:     o.SourceQuality,
+		CompressionLevel:  o.CompressionLevel,
+		IdentityProcessor: o.IdentityProcessor,
+		RawContent:        o.RawContent,
+		codings:           o.codings,
+		Charsets:          o.Charsets,
 	}
 
 	for i, s := range o.Langs {
@@ -86,7 +137,11 @@ func (o Offer) WithFunc(supplier dpkg.Supplier, language string, otherLanguages
 
 // With attaches response data to an offer.
 // The returned offer is a clone of the original offer, which is unchanged. This
-// allows base offers to be derived from.
+// allows base offers to be derived from. The clone is kept because an offer may be
+// shared, so it must not be modified. However, data values keep no iteration state
+// (each render uses its own data.Cursor), so an offer complete with its data can be
+// built once (e.g. at startup) and reused by concurrent requests; the cost of cloning
+// is then not incurred per request.
 //
 // The data can be a value (struct, slice, etc) or a data.Data. It may also be
 // nil, which means the method merely serves to add the language to the Offer's
@@ -174,6 +229,24 @@ func (o Offer) CanHandle406As(statusCode int) Offer {
 	return o
 }
 
+// WithSourceQuality sets the SourceQuality, which must be greater than 0 and no more than 1.
+// Otherwise this method panics.
+func (o Offer) WithSourceQuality(qs float64) Offer {
+	if qs <= 0 || qs > 1 {
+		panic(fmt.Sprintf("source quality %g must be in the range 0 < qs <= 1", qs))
+	}
+	o.SourceQuality = qs
+	return o
+}
+
+// WithCompressionLevel sets the CompressionLevel. This panics if the level is not valid for
+// compress/gzip.
+func (o Offer) WithCompressionLevel(level int) Offer {
+	checkCompressionLevel(level)
+	o.CompressionLevel = level
+	return o
+}
+
 // IsEmpty returns true if no data has been attached to this offer.
 func (o Offer) IsEmpty() bool {
 	return len(o.data) == 0 && len(o.Langs) == 1 && o.Langs[0] == "*"
@@ -189,6 +262,9 @@ func (o Offer) String() string {
 	buf := &strings.Builder{}
 	buf.WriteString("Accept: ")
 	buf.WriteString(o.ContentType.String())
+	if 0 < o.SourceQuality && o.SourceQuality < header.DefaultQuality {
+		fmt.Fprintf(buf, ";qs=%g", o.SourceQuality)
+	}
 	if len(o.data) > 0 {
 		buf.WriteString(". Accept-Language: ")
 		comma := ""
@@ -209,10 +285,15 @@ func (o Offer) BuildMatch(acceptedCT header.ContentType, lang string, statusCode
 	resolved := o.resolvedType(acceptedCT)
 
 	m := &Match{
-		ContentType: resolved,
-		Language:    lang,
-		Data:        o.Data(lang),
-		Render:      o.processor,
+		ContentType:       resolved,
+		Language:          lang,
+		Data:              o.Data(lang),
+		Render:            o.processor,
+		IdentityProcessor: o.IdentityProcessor,
+		RawContent:        o.RawContent,
+
+		compressionLevel: o.CompressionLevel,
+		codings:          o.codings,
 	}
 	if len(statusCodeOverride) > 0 {
 		m.StatusCodeOverride = statusCodeOverride[0]
@@ -246,7 +327,19 @@ func (o Offer) resolvedType(acceptedCT header.ContentType) header.ContentType {
 		// first 512 bytes but there is no attempt to do that here.
 	}
 
-	return header.ContentType{MediaType: t + "/" + s}
+	return header.ContentType{MediaType: t + "/" + s, Params: o.resolvedParams()}
+}
+
+// resolvedParams gets the offer's media type parameters, if any, so that they can be
+// echoed in the response. Any charset is excluded because that is negotiated separately.
+func (o Offer) resolvedParams() []header.KV {
+	var params []header.KV
+	for _, p := range o.Params {
+		if p.Key != "charset" {
+			params = append(params, p)
+		}
+	}
+	return params
 }
 
 // Data gets the data lodged for a given language (or language group).
@@ -279,7 +372,7 @@ func (e empty) Meta(dpkg.Chosen) (*dpkg.Metadata, error) {
 	panic("not reachable")
 }
 
-func (e empty) Content(dpkg.Chosen) (any, bool, error) {
+func (e empty) Content(dpkg.Chosen) dpkg.Cursor {
 	panic("not reachable")
 }
 
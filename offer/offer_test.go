@@ -2,6 +2,7 @@ package offer
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
 	"testing"
 
@@ -77,6 +78,41 @@ func Test_offer_with(t *testing.T) {
 	expect.Map(o4.data).ToHaveLength(t, 3)
 }
 
+func Test_offer_with_keeps_406_handling(t *testing.T) {
+	o1 := Of(nil, "text/plain").CanHandle406As(http.StatusBadRequest)
+	o2 := o1.With("foo", "en")
+
+	expect.Number(o2.Handle406As).ToBe(t, http.StatusBadRequest)
+}
+
+func Test_offer_source_quality(t *testing.T) {
+	o1 := Of(nil, "text/csv")
+	o2 := o1.WithSourceQuality(0.5)
+	o3 := o2.With("foo", "en")
+
+	expect.Number(o1.SourceQuality).ToBe(t, 1.0)
+	expect.Number(o2.SourceQuality).ToBe(t, 0.5)
+	expect.Number(o3.SourceQuality).ToBe(t, 0.5)
+	expect.String(o3.String()).ToBe(t, "Accept: text/csv;qs=0.5. Accept-Language: en")
+
+	defer func() {
+		expect.Any(recover()).Not().ToBeNil(t)
+	}()
+	o1.WithSourceQuality(0)
+}
+
+func Test_offer_compression_level(t *testing.T) {
+	o1 := Of(nil, "text/csv")
+	o2 := o1.WithCompressionLevel(9)
+
+	expect.Number(o2.CompressionLevel).ToBe(t, 9)
+
+	defer func() {
+		expect.Any(recover()).Not().ToBeNil(t)
+	}()
+	o1.WithCompressionLevel(10)
+}
+
 func TestOffersAllEmpty(t *testing.T) {
 	o1 := Of(nil, "text/plain")
 	o2 := Of(nil, "image/png")
@@ -154,7 +190,7 @@ func TestBuildMatch(t *testing.T) {
 		m := c.o.BuildMatch(c.accepted, "en", i+400)
 		m.Render = nil // comparing functions would always fail
 		if m.Data != nil {
-			expect.Value(m.Data.Content(dpkg.Chosen{})).ToBe(t, c.data)
+			expect.Value(m.Data.Content(dpkg.Chosen{}).Next()).ToBe(t, c.data)
 		}
 		m.Data = nil // because functions cannot be compared
 		expect.Value(*m).I(c.o).ToBe(t, c.m)
@@ -0,0 +1,194 @@
+package offer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/rickb777/acceptable/headername"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// ResponseWriter is the http.ResponseWriter given to processors (see Match.ApplyHeaders). It is
+// a pipeline that transcodes the response body into the negotiated charset, then compresses it
+// using the negotiated content coding, then writes it to the underlying response writer (or to
+// another sink, such as a buffer).
+//
+// It supports http.Flusher and can be unwrapped by http.ResponseController. It must be closed
+// after the response body has been written.
+type ResponseWriter struct {
+	rw         http.ResponseWriter
+	sink       io.Writer
+	charset    encoding.Encoding // nil when not transcoding
+	compressor io.WriteCloser    // nil when not compressing
+	transcoder *transcoder       // nil when not transcoding
+	body       io.Writer         // the top of the pipeline; nil until writing starts
+}
+
+// NewResponseWriter creates a ResponseWriter that writes to sink, which will usually be rw
+// itself. The charset encoding is optional (nil means no transcoding).
+func NewResponseWriter(rw http.ResponseWriter, sink io.Writer, charset encoding.Encoding) *ResponseWriter {
+	return &ResponseWriter{rw: rw, sink: sink, charset: charset}
+}
+
+// Header returns the header map of the underlying response writer.
+func (w *ResponseWriter) Header() http.Header {
+	return w.rw.Header()
+}
+
+// WriteHeader sends the response status code using the underlying response writer.
+func (w *ResponseWriter) WriteHeader(statusCode int) {
+	w.rw.WriteHeader(statusCode)
+}
+
+// Write writes part of the response body via the pipeline.
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	return w.pipeline().Write(b)
+}
+
+func (w *ResponseWriter) pipeline() io.Writer {
+	if w.body == nil {
+		w.body = w.sink
+		if w.compressor != nil {
+			w.body = w.compressor
+		}
+		if w.charset != nil {
+			w.transcoder = newTranscoder(w.body, w.charset.NewEncoder())
+			w.body = w.transcoder
+		}
+	}
+	return w.body
+}
+
+// SetContentEncoding compresses the response body using a content coding in Compressors, and
+// sets the Content-Encoding header accordingly. This must be called before anything has been
+// written and at most once.
+func (w *ResponseWriter) SetContentEncoding(coding string, level int) error {
+	return w.setContentEncoding(Compressors, coding, level)
+}
+
+func (w *ResponseWriter) setContentEncoding(compressors map[string]Compressor, coding string, level int) error {
+	if w.body != nil {
+		return errors.New("content encoding cannot be set after writing has started")
+	}
+	if w.compressor != nil {
+		return errors.New("content encoding has already been set")
+	}
+
+	compressor, exists := compressors[coding]
+	if !exists {
+		return fmt.Errorf("%s is not an available content coding", coding)
+	}
+
+	cw, err := compressor(w.sink, level)
+	if err != nil {
+		return err
+	}
+
+	w.compressor = cw
+	w.Header().Set(headername.ContentEncoding, coding)
+	return nil
+}
+
+// IsIdentity reports whether the response body is written to the sink unchanged, i.e. it
+// is neither transcoded nor compressed.
+func (w *ResponseWriter) IsIdentity() bool {
+	return w.charset == nil && w.compressor == nil
+}
+
+// Flush sends any buffered data to the client, including any data held by the transcoder and
+// the compressor. When the sink is not the underlying response writer, only the transcoder and
+// the compressor are flushed.
+func (w *ResponseWriter) Flush() {
+	if w.transcoder != nil {
+		_ = w.transcoder.Flush()
+	}
+	if f, ok := w.compressor.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if w.sink == io.Writer(w.rw) {
+		_ = http.NewResponseController(w.rw).Flush()
+	}
+}
+
+// Close flushes the transcoder and closes the compressor, if either is in use.
+// The underlying response writer is not closed.
+func (w *ResponseWriter) Close() (err error) {
+	if w.transcoder != nil {
+		err = w.transcoder.Close()
+	}
+	if w.compressor != nil {
+		if e := w.compressor.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Unwrap returns the underlying response writer; this is used by http.ResponseController.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.rw
+}
+
+//-------------------------------------------------------------------------------------------------
+
+// transcoder is similar to transform.Writer except that it can also be flushed. A flush ends the
+// transformation as if at the end of the input, so that stateful encodings (e.g. ISO-2022-JP)
+// write their closing escape sequence; the encoder is then ready to continue.
+type transcoder struct {
+	dst io.Writer
+	t   transform.Transformer
+	src []byte // input not yet transformed, i.e. an incomplete character
+	buf []byte
+}
+
+func newTranscoder(dst io.Writer, t transform.Transformer) *transcoder {
+	return &transcoder{dst: dst, t: t, buf: make([]byte, 4096)}
+}
+
+func (tc *transcoder) Write(b []byte) (int, error) {
+	tc.src = append(tc.src, b...)
+	if err := tc.transform(false); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush writes everything transformed so far. This does nothing while an incomplete
+// character is pending.
+func (tc *transcoder) Flush() error {
+	if len(tc.src) > 0 {
+		return nil
+	}
+	return tc.transform(true)
+}
+
+// Close writes the remainder of the transformation. The destination is not closed.
+func (tc *transcoder) Close() error {
+	return tc.transform(true)
+}
+
+func (tc *transcoder) transform(atEOF bool) error {
+	for {
+		nDst, nSrc, err := tc.t.Transform(tc.buf, tc.src, atEOF)
+		if nDst > 0 {
+			if _, werr := tc.dst.Write(tc.buf[:nDst]); werr != nil {
+				return werr
+			}
+		}
+		tc.src = tc.src[:copy(tc.src, tc.src[nSrc:])]
+
+		switch {
+		case err == transform.ErrShortDst:
+			if nDst == 0 && nSrc == 0 {
+				tc.buf = make([]byte, 2*len(tc.buf))
+			}
+		case err == transform.ErrShortSrc && !atEOF:
+			return nil // the incomplete character is kept until more is written
+		default:
+			return err
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package offer_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dpkg "github.com/rickb777/acceptable/data"
+	"github.com/rickb777/acceptable/header"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+func TestResponseWriter_should_compress_and_transcode_via_processor(t *testing.T) {
+	// Given ...
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(AcceptCharset, "iso-8859-1")
+	req.Header.Set(AcceptEncoding, "gzip")
+	rw := httptest.NewRecorder()
+
+	m := offer.Match{ContentType: header.ContentType{MediaType: "text/plain"}, Charset: "iso-8859-1"}
+	w := m.ApplyHeaders(rw)
+
+	p := offer.TXTProcessor(offer.MidCompression)
+
+	// When ...
+	err := p(w, req, dpkg.Of("café"), dpkg.Chosen{Charset: m.Charset})
+	expect.Error(w.Close()).Not().ToHaveOccurred(t)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Header().Get(ContentType)).ToBe(t, "text/plain;charset=windows-1252")
+	expect.String(rw.Header().Get(ContentEncoding)).ToBe(t, "gzip")
+	expect.String(rw.Header().Get(Vary)).ToBe(t, "Accept-Encoding")
+
+	zr, err := gzip.NewReader(bytes.NewReader(rw.Body.Bytes()))
+	expect.Error(err).Not().ToHaveOccurred(t)
+	b, _ := io.ReadAll(zr)
+	expect.Slice(b).ToBe(t, 'c', 'a', 'f', 0xE9, '\n')
+}
+
+func TestResponseWriter_should_flush_compressed_data(t *testing.T) {
+	// Given ...
+	rw := httptest.NewRecorder()
+
+	m := offer.Match{ContentType: header.ContentType{MediaType: "text/plain"}, ContentEncoding: "gzip"}
+	w := m.ApplyHeaders(rw)
+	io.WriteString(w, "Hello")
+
+	// When ...
+	http.NewResponseController(w).Flush()
+
+	// Then ...
+	expect.Bool(rw.Flushed).ToBeTrue(t)
+	zr, err := gzip.NewReader(bytes.NewReader(rw.Body.Bytes()))
+	expect.Error(err).Not().ToHaveOccurred(t)
+	b := make([]byte, 5)
+	_, err = io.ReadFull(zr, b)
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(string(b)).ToBe(t, "Hello")
+}
+
+func TestResponseWriter_should_write_to_sink(t *testing.T) {
+	// Given ...
+	rw := httptest.NewRecorder()
+	buf := &bytes.Buffer{}
+
+	m := offer.Match{ContentType: header.ContentType{MediaType: "text/plain"}, Charset: "windows-1252"}
+	w := m.ApplyHeadersTo(rw, buf)
+
+	// When ...
+	io.WriteString(w, "café")
+	w.Close()
+
+	// Then ...
+	expect.Slice(buf.Bytes()).ToBe(t, 'c', 'a', 'f', 0xE9)
+	expect.Number(rw.Body.Len()).ToBe(t, 0)
+	expect.String(rw.Header().Get(ContentType)).ToBe(t, "text/plain;charset=windows-1252")
+}
+
+func TestResponseWriter_should_refuse_content_encoding_after_writing(t *testing.T) {
+	// Given ...
+	rw := httptest.NewRecorder()
+	w := offer.NewResponseWriter(rw, rw, nil)
+	io.WriteString(w, "x")
+
+	// When ...
+	err := w.SetContentEncoding("gzip", offer.MidCompression)
+
+	// Then ...
+	expect.Error(err).ToHaveOccurred(t)
+	expect.String(rw.Header().Get(ContentEncoding)).ToBe(t, "")
+}
+
+func TestResponseWriter_should_flush_transcoded_data(t *testing.T) {
+	// Given ...
+	rw := httptest.NewRecorder()
+
+	m := offer.Match{ContentType: header.ContentType{MediaType: "text/plain"}, Charset: "iso-2022-jp"}
+	w := m.ApplyHeaders(rw)
+	io.WriteString(w, "日本")
+
+	// When ...
+	http.NewResponseController(w).Flush()
+
+	// Then ...
+	expect.Bool(rw.Flushed).ToBeTrue(t)
+	expect.String(rw.Body.String()).ToBe(t, "\x1b$BF|K\\\x1b(B") // the encoder has returned to ASCII
+
+	// When ...
+	io.WriteString(w, "語")
+	expect.Error(w.Close()).Not().ToHaveOccurred(t)
+
+	// Then ...
+	expect.String(rw.Body.String()).ToBe(t, "\x1b$BF|K\\\x1b(B\x1b$B8l\x1b(B")
+}
@@ -5,23 +5,19 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 
 	dpkg "github.com/rickb777/acceptable/data"
 	"github.com/rickb777/acceptable/internal"
 )
 
 // Text returns an Offer for text/subtype content using TXTProcessor.
-// The response will use gzip compression (see [GZIPLevel]) when the client requests it.
+// The response will be compressed (see [GZIPLevel]) when the client accepts a content coding such as gzip.
 func Text(subtype string) Offer {
-	if strings.ContainsRune(subtype, '/') {
-		panic(fmt.Sprintf("subtype %q must not contain '/'", subtype))
-	}
-	return Of(TXTProcessor(GZIPLevel), "text/"+subtype)
+	return DefaultConfig().Text(subtype)
 }
 
 // TextPlain returns an Offer for text/plain content using TXTProcessor.
-// The response will use gzip compression (see [GZIPLevel]) when the client requests it.
+// The response will be compressed (see [GZIPLevel]) when the client accepts a content coding such as gzip.
 func TextPlain() Offer { return Text("plain") }
 
 // TXTProcessor creates an output processor that serialises strings in a form suitable for text/* responses (especially
@@ -43,7 +39,7 @@ func TextPlain() Offer { return Text("plain") }
 // Because it handles io.Reader and io.WriterTo, TXTProcessor can be used to stream large responses (without any
 // further encoding).
 func TXTProcessor(gzipLevel int) Processor {
-	return GZIPProcessor(gzipLevel, txtProcessor())
+	return EncodingProcessor(gzipLevel, txtProcessor())
 }
 
 func txtProcessor() Processor {
@@ -52,9 +48,15 @@ func txtProcessor() Processor {
 
 		more := data != nil
 
+		var content dpkg.Cursor
+		if more {
+			content = data.Content(chosen)
+			defer content.Close()
+		}
+
 		for more {
 			var d any
-			d, more, err = data.Content(chosen)
+			d, more, err = content.Next()
 			if err != nil {
 				return err
 			}
@@ -2,19 +2,19 @@ package offer
 
 import (
 	"encoding/xml"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 
-	"github.com/rickb777/acceptable/contenttype"
 	dpkg "github.com/rickb777/acceptable/data"
 	"github.com/rickb777/acceptable/internal"
 )
 
 // XML constructs an XML Offer easily.
-// The response will use gzip compression (see [GZIPLevel]) when the client requests it.
+// The response will be compressed (see [GZIPLevel]) when the client accepts a content coding such as gzip.
 func XML(root string, indent ...string) Offer {
-	return Of(XMLProcessor(GZIPLevel, root, indent...), contenttype.ApplicationXML)
+	return DefaultConfig().XML(root, indent...)
 }
 
 // XMLProcessor creates a new processor for XML with root element and optional indentation. This
@@ -24,8 +24,10 @@ func XML(root string, indent ...string) Offer {
 // can be a name such as "root" or an XML element such as "<html lang='en'>".
 //
 // The optional indent argument is a string usually of zero or more space characters.
+//
+// When the response charset is not utf-8, an XML declaration stating the encoding is written first.
 func XMLProcessor(gzipLevel int, root string, indent ...string) Processor {
-	return GZIPProcessor(gzipLevel, xmlProcessor(root, indent...))
+	return EncodingProcessor(gzipLevel, xmlProcessor(root, indent...))
 }
 
 func xmlProcessor(root string, indent ...string) Processor {
@@ -49,11 +51,19 @@ func xmlProcessor(root string, indent ...string) Processor {
 
 		enc := xml.NewEncoder(p)
 
-		d, more, err := data.Content(chosen)
+		content := data.Content(chosen)
+		defer content.Close()
+
+		d, more, err := content.Next()
 		if err != nil {
 			return err
 		}
 
+		if chosen.Charset != "" && chosen.Charset != utf8 {
+			// utf-8 is the default so a declaration is only needed for other charsets
+			fmt.Fprintf(p, "<?xml version=\"1.0\" encoding=\"%s\"?>\n", chosen.Charset)
+		}
+
 		var newline []byte
 		if len(in) > 0 {
 			newline = []byte{'\n'}
@@ -77,7 +87,7 @@ func xmlProcessor(root string, indent ...string) Processor {
 		for stillMore {
 			p.Write(newline)
 
-			d, stillMore, err = data.Content(chosen)
+			d, stillMore, err = content.Next()
 			if err != nil {
 				return err
 			}
@@ -40,6 +40,30 @@ func TestXMLShouldWriteLazyResponseBody(t *testing.T) {
 	expect.String(rw.Body.String()).ToBe(t, "<ValidXMLUser><Name>Joe Bloggs</Name></ValidXMLUser>\n")
 }
 
+func TestXMLShouldWriteDeclarationForCharset(t *testing.T) {
+	req := &http.Request{}
+	rw := httptest.NewRecorder()
+
+	model := &ValidXMLUser{
+		"Joe Bloggs",
+	}
+
+	match := offer.Match{
+		ContentType: header.ContentType{MediaType: "application/xml"},
+		Language:    "en",
+		Charset:     "windows-1252",
+		Data:        dpkg.Of(model),
+	}
+
+	p := offer.XMLProcessor(0, "xml")
+
+	w := match.ApplyHeaders(rw)
+	err := p(w, req, match.Data, dpkg.Chosen{Language: match.Language, Charset: match.Charset})
+
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Body.String()).ToBe(t, "<?xml version=\"1.0\" encoding=\"windows-1252\"?>\n<ValidXMLUser><Name>Joe Bloggs</Name></ValidXMLUser>\n")
+}
+
 func TestXMLShouldWriteSequenceResponseBody(t *testing.T) {
 	req := &http.Request{}
 	rw := httptest.NewRecorder()
@@ -0,0 +1,129 @@
+package offer
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	dpkg "github.com/rickb777/acceptable/data"
+)
+
+// YAMLSequence selects how a sequence of data items is written as YAML.
+type YAMLSequence int
+
+const (
+	// YAMLDocuments writes each item as a separate YAML document, separated by "---".
+	YAMLDocuments YAMLSequence = iota
+	// YAMLList writes the items as a single YAML document containing a list.
+	YAMLList
+)
+
+// YAML constructs a YAML Offer easily. NewYAMLEncoder must have been set.
+// The response will be compressed (see [GZIPLevel]) when the client accepts a content coding such as gzip.
+func YAML(style ...YAMLSequence) Offer {
+	return DefaultConfig().YAML(style...)
+}
+
+// YAMLProcessor creates a new processor for YAML. This converts a data item (or a sequence of
+// data items) into YAML using NewYAMLEncoder.
+//
+// When writing a sequence of items, the optional style argument chooses between separate YAML
+// documents (the default) and a single list.
+func YAMLProcessor(gzipLevel int, style ...YAMLSequence) Processor {
+	mustHaveEncoder(NewYAMLEncoder != nil, "NewYAMLEncoder")
+	return EncodingProcessor(gzipLevel, yamlProcessor(defaultYAMLEncoder, style...))
+}
+
+// defaultYAMLEncoder defers to NewYAMLEncoder at the time of use.
+func defaultYAMLEncoder(w io.Writer) YAMLEncoder { return NewYAMLEncoder(w) }
+
+func yamlProcessor(newEncoder func(w io.Writer) YAMLEncoder, style ...YAMLSequence) Processor {
+	asList := len(style) > 0 && style[0] == YAMLList
+
+	return func(w io.Writer, _ *http.Request, data dpkg.Data, chosen dpkg.Chosen) (err error) {
+		content := data.Content(chosen)
+		defer content.Close()
+
+		item, more, err := content.Next()
+		if err != nil {
+			return err
+		}
+
+		if !more {
+			return encodeYAML(w, newEncoder, item)
+		}
+
+		buf := &bytes.Buffer{}
+		for first := true; ; first = false {
+			if asList {
+				buf.Reset()
+				if err = encodeYAML(buf, newEncoder, item); err != nil {
+					return err
+				}
+				if err = writeYAMLListItem(w, buf.String()); err != nil {
+					return err
+				}
+			} else {
+				if !first {
+					if _, err = io.WriteString(w, "---\n"); err != nil {
+						return err
+					}
+				}
+				if err = encodeYAML(w, newEncoder, item); err != nil {
+					return err
+				}
+			}
+
+			if !more {
+				return nil
+			}
+
+			item, more, err = content.Next()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func encodeYAML(w io.Writer, newEncoder func(w io.Writer) YAMLEncoder, item any) error {
+	enc := newEncoder(w)
+	if err := enc.Encode(item); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// writeYAMLListItem writes an encoded item as an entry in a block sequence.
+func writeYAMLListItem(w io.Writer, encoded string) error {
+	lines := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n")
+	b := &strings.Builder{}
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			b.WriteString("- ")
+		case line != "":
+			b.WriteString("  ")
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// YAMLEncoder summarises the key methods of the YAML encoder.
+type YAMLEncoder interface {
+	Encode(any) error
+	Close() error
+}
+
+// NewYAMLEncoder is a pluggable YAML encoder. It is nil initially, so that this package does
+// not depend on any particular YAML library; it must be set before YAML offers are constructed,
+// e.g. using gopkg.in/yaml.v3
+//
+//	offer.NewYAMLEncoder = func(w io.Writer) offer.YAMLEncoder { return yaml.NewEncoder(w) }
+//
+// Config.NewYAMLEncoder can be used instead for independent settings.
+var NewYAMLEncoder func(w io.Writer) YAMLEncoder
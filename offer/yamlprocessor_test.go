@@ -0,0 +1,132 @@
+package offer_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rickb777/acceptable/contenttype"
+	dpkg "github.com/rickb777/acceptable/data"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+type Account struct {
+	Name  string
+	Roles []string
+}
+
+// accountEncoder writes real YAML, laid out as gopkg.in/yaml.v3 does, but only for Account values.
+type accountEncoder struct {
+	w io.Writer
+}
+
+func (e accountEncoder) Encode(v any) error {
+	a, ok := v.(Account)
+	if !ok {
+		return fmt.Errorf("unsupported %T", v)
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "name: %s\n", a.Name)
+	if len(a.Roles) == 0 {
+		b.WriteString("roles: []\n")
+	} else {
+		b.WriteString("roles:\n")
+		for _, r := range a.Roles {
+			fmt.Fprintf(b, "    - %s\n", r)
+		}
+	}
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func (e accountEncoder) Close() error { return nil }
+
+func TestYAMLShouldWriteResponseBody(t *testing.T) {
+	offer.NewYAMLEncoder = func(w io.Writer) offer.YAMLEncoder { return accountEncoder{w: w} }
+	defer func() { offer.NewYAMLEncoder = nil }()
+
+	accounts := []Account{{Name: "Ann", Roles: []string{"admin", "user"}}, {Name: "Joe"}}
+
+	cases := []struct {
+		style    []offer.YAMLSequence
+		data     dpkg.Data
+		expected string
+	}{
+		{
+			data:     dpkg.Of(accounts[0]),
+			expected: "name: Ann\nroles:\n    - admin\n    - user\n",
+		},
+		{
+			data:     dpkg.Seq(slices.Values(accounts)),
+			expected: "name: Ann\nroles:\n    - admin\n    - user\n---\nname: Joe\nroles: []\n",
+		},
+		{
+			style:    []offer.YAMLSequence{offer.YAMLList},
+			data:     dpkg.Seq(slices.Values(accounts)),
+			expected: "- name: Ann\n  roles:\n      - admin\n      - user\n- name: Joe\n  roles: []\n",
+		},
+		{
+			style:    []offer.YAMLSequence{offer.YAMLList},
+			data:     dpkg.Of(accounts[1]),
+			expected: "name: Joe\nroles: []\n",
+		},
+	}
+
+	for i, c := range cases {
+		req := &http.Request{}
+		rw := httptest.NewRecorder()
+
+		o := offer.YAML(c.style...).With(c.data, "*")
+		m := o.BuildMatch(o.ContentType, "*")
+
+		w := m.ApplyHeaders(rw)
+		err := m.Render(w, req, m.Data, dpkg.Chosen{})
+		w.Close()
+
+		expect.Error(err).I(i).Not().ToHaveOccurred(t)
+		expect.String(rw.Header().Get(ContentType)).I(i).ToBe(t, contenttype.ApplicationYAML)
+		expect.String(rw.Body.String()).I(i).ToBe(t, c.expected)
+	}
+}
+
+type fakeYAMLEncoder struct {
+	w io.Writer
+}
+
+func (e fakeYAMLEncoder) Encode(v any) error {
+	_, err := io.WriteString(e.w, "fake\n")
+	return err
+}
+
+func (e fakeYAMLEncoder) Close() error { return nil }
+
+func TestConfig_should_use_its_own_yaml_encoder(t *testing.T) {
+	cfg := offer.Config{
+		NewYAMLEncoder: func(w io.Writer) offer.YAMLEncoder { return fakeYAMLEncoder{w: w} },
+	}
+
+	p := cfg.YAMLProcessor(offer.YAMLList)
+
+	req := &http.Request{}
+	rw := httptest.NewRecorder()
+
+	err := p(rw, req, dpkg.Seq(slices.Values([]int{1, 2})), dpkg.Chosen{})
+
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(rw.Body.String()).ToBe(t, "- fake\n- fake\n")
+}
+
+func TestYAML_should_panic_without_an_encoder(t *testing.T) {
+	defer func() {
+		expect.Any(recover()).Not().ToBeNil(t)
+	}()
+	offer.YAML()
+}
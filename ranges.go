@@ -0,0 +1,101 @@
+package acceptable
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	dpkg "github.com/rickb777/acceptable/data"
+	"github.com/rickb777/acceptable/header"
+	"github.com/rickb777/acceptable/headername"
+	offerpkg "github.com/rickb777/acceptable/offer"
+)
+
+// rangesApply determines whether a response can use byte ranges (RFC-9110 section 14). This
+// applies only to 200-OK responses to GET and HEAD requests, when enabled.
+func (ctx RespondWith) rangesApply(req *http.Request, best *offerpkg.Match) bool {
+	return ctx.AcceptRanges &&
+		(req.Method == http.MethodGet || req.Method == http.MethodHead) &&
+		(ctx.StatusCode == 0 || ctx.StatusCode == http.StatusOK) &&
+		best.StatusCodeOverride == 0
+}
+
+// isRangeRequest determines whether the request asks for part of the representation.
+// Range is only defined for GET requests.
+func isRangeRequest(req *http.Request) bool {
+	return req.Method == http.MethodGet && req.Header.Get(headername.Range) != ""
+}
+
+// renderRanges renders part of the representation, or all of it if If-Range does not hold,
+// using http.ServeContent to handle Range and If-Range. This sets Accept-Ranges, Content-Range
+// and Content-Length, and uses multipart/byteranges for multiple ranges.
+//
+// When the offer's processor writes its data unaltered (see offer.Offer.RawContent), the data
+// is seekable (an io.ReadSeeker) and the response is neither transcoded nor compressed, it is
+// served directly. Otherwise, the rendered output has already been directed
+// to buf by w, so this is served instead.
+func renderRanges(best *offerpkg.Match, rw http.ResponseWriter, w *offerpkg.ResponseWriter, buf *bytes.Buffer, req *http.Request, chosen dpkg.Chosen) error {
+	content := best.Data.Content(chosen)
+	defer content.Close()
+
+	value, more, err := content.Next()
+	if err != nil {
+		return err
+	}
+
+	if rs, ok := value.(io.ReadSeeker); ok && !more && best.RawContent && w.IsIdentity() {
+		serveRanges(rw, req, rs)
+		if c, ok := rs.(io.Closer); ok {
+			return c.Close()
+		}
+		return nil
+	}
+
+	// the data obtained above is given to the processor instead of getting it again
+	data := &prefetched{Data: best.Data, content: content, value: value, more: more}
+
+	err = best.Render(w, req, data, chosen)
+	if e := w.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+
+	serveRanges(rw, req, bytes.NewReader(buf.Bytes()))
+	return nil
+}
+
+// serveRanges serves the requested ranges of content. The ETag and Last-Modified headers
+// have been set from the metadata already; these are used for If-Range.
+func serveRanges(rw http.ResponseWriter, req *http.Request, content io.ReadSeeker) {
+	lastModified, _ := header.ParseHTTPDateTime(rw.Header().Get(headername.LastModified))
+	http.ServeContent(rw, req, "", lastModified, content)
+}
+
+// prefetched is data for which the first content has already been obtained. It is also
+// the cursor for the remaining content.
+type prefetched struct {
+	dpkg.Data
+	content dpkg.Cursor
+	value   any
+	more    bool
+	used    bool
+}
+
+func (p *prefetched) Content(dpkg.Chosen) dpkg.Cursor {
+	return p
+}
+
+func (p *prefetched) Next() (any, bool, error) {
+	if !p.used {
+		p.used = true
+		return p.value, p.more, nil
+	}
+	return p.content.Next()
+}
+
+// Close does nothing; the underlying cursor is closed by renderRanges.
+func (p *prefetched) Close() error {
+	return nil
+}
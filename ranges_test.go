@@ -0,0 +1,218 @@
+package acceptable_test
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rickb777/acceptable"
+	"github.com/rickb777/acceptable/data"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+func Test_should_serve_range_of_seekable_data(t *testing.T) {
+	// Given ...
+	a := offer.Of(offer.BinaryProcessor(0), "application/octet-stream")
+	a.RawContent = true
+	a = a.With(data.Of(strings.NewReader("Hello world")).ETag("abc123"), "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Range, "bytes=6-")
+	req.Header.Add(IfRange, `"abc123"`)
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RespondWith{AcceptRanges: true}.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 206)
+	expect.String(w.Header().Get(AcceptRanges)).ToBe(t, "bytes")
+	expect.String(w.Header().Get(ContentRange)).ToBe(t, "bytes 6-10/11")
+	expect.String(w.Header().Get(ContentLength)).ToBe(t, "5")
+	expect.String(w.Header().Get(ETag)).ToBe(t, `"abc123"`)
+	expect.String(w.Body.String()).ToBe(t, "world")
+}
+
+func Test_should_serve_range_of_processed_seekable_data(t *testing.T) {
+	// Given ...
+	type record struct {
+		*strings.Reader
+		Name string
+	}
+	a := offer.JSON().With(data.Of(record{Reader: strings.NewReader("Hello world"), Name: "Ann"}), "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Range, "bytes=0-")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RespondWith{AcceptRanges: true}.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 206)
+	expect.String(w.Header().Get(ContentType)).ToBe(t, "application/json")
+	expect.String(w.Body.String()).ToBe(t, `{"Name":"Ann"}`+"\n")
+}
+
+func Test_should_serve_multiple_ranges_of_rendered_output(t *testing.T) {
+	// Given ...
+	a := offer.CSV().With([][]string{{"a", "b"}, {"c", "d"}}, "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Range, "bytes=0-2, 4-6")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RespondWith{AcceptRanges: true}.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 206)
+
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get(ContentType))
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(mediaType).ToBe(t, "multipart/byteranges")
+
+	mr := multipart.NewReader(w.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		expect.Error(err).Not().ToHaveOccurred(t)
+		expect.String(p.Header.Get(ContentType)).ToBe(t, "text/csv;charset=utf-8")
+		b, _ := io.ReadAll(p)
+		parts = append(parts, string(b))
+	}
+	expect.Slice(parts).ToBe(t, "a,b", "c,d")
+}
+
+func Test_should_serve_range_of_compressed_output(t *testing.T) {
+	// Given ...
+	a := offer.TextPlain().With(strings.Repeat("Hello world ", 10), "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(AcceptEncoding, "gzip")
+	req.Header.Add(Range, "bytes=0-9")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RespondWith{AcceptRanges: true}.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 206)
+	expect.String(w.Header().Get(ContentEncoding)).ToBe(t, "gzip")
+	expect.String(w.Header().Get(ContentRange)).ToContain(t, "bytes 0-9/")
+	expect.Number(w.Body.Len()).ToBe(t, 10)
+	expect.Slice(w.Body.Bytes()[:2]).ToBe(t, 0x1f, 0x8b) // the gzip header
+}
+
+func Test_should_serve_whole_content_when_if_range_does_not_match(t *testing.T) {
+	lastModified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []string{
+		`"old"`,
+		"Wed, 01 Jan 2020 00:00:00 GMT",
+	}
+
+	for _, ifRange := range cases {
+		// Given ...
+		a := offer.TextPlain().With(data.Of("Hello world").ETag("abc123").LastModified(lastModified), "*")
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add(Range, "bytes=0-4")
+		req.Header.Add(IfRange, ifRange)
+		w := httptest.NewRecorder()
+
+		// When ...
+		err := acceptable.RespondWith{AcceptRanges: true}.RenderBestMatch(w, req, a)
+
+		// Then ...
+		expect.Error(err).Not().ToHaveOccurred(t)
+		expect.Number(w.Code).I(ifRange).ToBe(t, 200)
+		expect.String(w.Header().Get(ContentRange)).I(ifRange).ToBe(t, "")
+		expect.String(w.Body.String()).I(ifRange).ToBe(t, "Hello world\n")
+	}
+}
+
+func Test_should_serve_range_when_if_range_date_matches(t *testing.T) {
+	// Given ...
+	lastModified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := offer.TextPlain().With(data.Of("Hello world").LastModified(lastModified), "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Range, "bytes=0-4")
+	req.Header.Add(IfRange, "Thu, 02 Jan 2020 03:04:05 GMT")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RespondWith{AcceptRanges: true}.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 206)
+	expect.String(w.Body.String()).ToBe(t, "Hello")
+}
+
+func Test_should_advertise_ranges_without_range_request(t *testing.T) {
+	// Given ...
+	a := offer.TextPlain().With("Hello world", "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RespondWith{AcceptRanges: true}.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Header().Get(AcceptRanges)).ToBe(t, "bytes")
+	expect.String(w.Body.String()).ToBe(t, "Hello world\n")
+}
+
+func Test_should_ignore_range_unless_enabled(t *testing.T) {
+	// Given ...
+	a := offer.TextPlain().With("Hello world", "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Range, "bytes=0-4")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Header().Get(AcceptRanges)).ToBe(t, "")
+	expect.String(w.Body.String()).ToBe(t, "Hello world\n")
+}
+
+func Test_should_reject_unsatisfiable_range(t *testing.T) {
+	// Given ...
+	a := offer.TextPlain().With("Hello world", "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Range, "bytes=100-200")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RespondWith{AcceptRanges: true}.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 416)
+	expect.String(w.Header().Get(ContentRange)).ToBe(t, "bytes */12")
+}
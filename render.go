@@ -1,8 +1,11 @@
 package acceptable
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/rickb777/acceptable/contenttype"
 	dpkg "github.com/rickb777/acceptable/data"
@@ -13,7 +16,11 @@ import (
 // NoMatchAccepted is a function used by RenderBestMatch when no match has been found.
 // Replace this as needed. Note that offer.Offer can also handle 406-Not-Accepted cases,
 // allowing customised error responses.
-var NoMatchAccepted = func(rw http.ResponseWriter, _ *http.Request) {
+//
+// This is the default for DefaultNegotiator; use Negotiator for independent settings.
+var NoMatchAccepted = notAcceptable
+
+func notAcceptable(rw http.ResponseWriter, _ *http.Request) {
 	rw.Header().Set(headername.ContentType, contenttype.TextPlain+";"+contenttype.CharsetUTF8)
 	rw.WriteHeader(http.StatusNotAcceptable)
 	defaultNotAcceptableMessage := http.StatusText(http.StatusNotAcceptable) + "\n"
@@ -26,6 +33,16 @@ type RespondWith struct {
 	StatusCode int
 	// Template name is only required when using template renderers
 	Template string
+	// Negotiator provides the settings for content negotiation; if nil, DefaultNegotiator is used
+	Negotiator *Negotiator
+	// AcceptRanges enables byte range requests for 200-OK responses (see RFC-9110 section 14)
+	AcceptRanges bool
+	// AutoETag enables strong entity tags computed from the rendered response, for GET and HEAD
+	// requests whose data has no entity tag of its own
+	AutoETag bool
+	// RepresentationETags makes the strong entity tags of the data specific to the content type,
+	// language and content coding of each response, for GET and HEAD requests
+	RepresentationETags bool
 }
 
 // RenderBestMatch calls [RespondWith.RenderBestMatch] using default status code (200-OK)
@@ -45,28 +62,64 @@ func RenderBestMatch(rw http.ResponseWriter, req *http.Request, available ...off
 //
 // If no match is found, a fallback match is sought. If a fallback offer is matched, its
 // Handle406As status code will be used, and its data is rendered using its processor; no
-// further processing follows. Otherwise, NoMatchAccepted is called and processing ends.
+// further processing follows. Otherwise, the negotiator's NoMatchAccepted is called and
+// processing ends.
 //
 // If a match is found, the following happens.
 //
 // If the matched offer has empty data, the response will be 204-No Content; no further
 // processing occurs.
 //
-// A check is then made for a conditional request (ETag, If-Modified-Since etc). If this is
-// successful, the response is 304-Not Modified and no response rendering occurs.
+// For GET and HEAD requests, a check is then made for a conditional request (If-None-Match,
+// If-Match etc). If a precondition applies, the response is 304-Not Modified or 412-Precondition
+// Failed and no response rendering occurs. Handlers for other methods should use
+// data.EvaluatePreconditions before they make any change.
 //
 // Finally, if statusCode is non-zero it is applied to the response (200-OK otherwise).
-// Then the matched offer's data is rendered using the offer's processor.
+// Then the matched offer's data is rendered using the offer's processor. For HEAD requests,
+// rendering is skipped and the content is not obtained; the Content-Length header is set when
+// the data's metadata provides it and the response is neither transcoded nor compressed.
+// However, when the offer's processor may compress for itself (see offer.Offer.IdentityProcessor)
+// and the client accepts a content coding, the content is rendered and discarded so that the
+// Content-Encoding and Content-Length headers are the same as for GET.
+//
+// When AcceptRanges is set, a GET request with a Range header receives 206-Partial Content
+// containing the requested byte ranges, provided that any If-Range condition holds (this
+// is checked using the ETag and Last-Modified from the data's metadata). Seekable data (an
+// io.ReadSeeker provided by Data.Content) is used directly when the offer's processor writes
+// it unaltered (see offer.Offer.RawContent) and neither transcoding nor compression is needed;
+// otherwise, the rendered output is buffered.
+//
+// When AutoETag is set and the data's metadata has no entity tag, the rendered output is
+// buffered and hashed to provide a strong entity tag. This depends on the content type,
+// language and content coding as well as the content, so each representation has its own
+// tag. The conditional request check is made after rendering in this case, so a matching
+// If-None-Match still gives 304-Not Modified, although the rendering cost is not avoided.
+//
+// When RepresentationETags is set, a strong entity tag from the data's metadata is given a
+// suffix derived from the content type, language and content coding. This means that each
+// representation has its own tag, as required for strong validators (RFC-9110 section 8.8.3).
+// Handlers for other methods (e.g. PUT with If-Match) should bear in mind that clients will
+// have these derived entity tags. Weak entity tags are never altered.
 func (ctx RespondWith) RenderBestMatch(rw http.ResponseWriter, req *http.Request, available ...offerpkg.Offer) error {
 	if offerpkg.Offers(available).AllEmpty() {
 		rw.WriteHeader(http.StatusNoContent)
 		return nil
 	}
 
-	best := BestRequestMatch(req, available...)
+	n := ctx.Negotiator
+	if n == nil {
+		n = DefaultNegotiator()
+	}
+
+	best := n.BestRequestMatch(req, available...)
 
 	if best == nil {
-		NoMatchAccepted(rw, req)
+		if n.NoMatchAccepted != nil {
+			n.NoMatchAccepted(rw, req)
+		} else {
+			notAcceptable(rw, req)
+		}
 		return nil
 	}
 
@@ -74,34 +127,132 @@ func (ctx RespondWith) RenderBestMatch(rw http.ResponseWriter, req *http.Request
 		panic(fmt.Sprintf("misconfigured offers for %s;charset=%s;lang=%s", best.MediaType, best.Charset, best.Language))
 	}
 
-	w := best.ApplyHeaders(rw)
+	if best.Data == nil && best.StatusCodeOverride == 0 {
+		// there is no content, so there is no content coding either
+		noContent := *best
+		noContent.ContentEncoding = ""
+		noContent.ApplyHeaders(rw)
+		rw.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	chosen := dpkg.Chosen{Template: ctx.Template, Language: best.Language, Charset: best.Charset, Request: req}
 
-	chosen := dpkg.Chosen{Template: ctx.Template, Language: best.Language}
+	ranges := ctx.rangesApply(req, best)
+	rangeRequest := ranges && isRangeRequest(req)
+
+	known, err := ctx.knownDataFor(req, best, chosen)
+	if err != nil {
+		return err
+	}
+
+	var buf *bytes.Buffer
+	var counter *countingWriter
+	sink := io.Writer(rw)
+	if rangeRequest || (known != nil && known.fromContent) {
+		buf = &bytes.Buffer{}
+		sink = buf
+	} else if req.Method == http.MethodHead && mayCompressItself(best, req) {
+		// the processor has to be run to discover the headers
+		counter = &countingWriter{}
+		sink = counter
+	}
+
+	w := best.ApplyHeadersTo(rw, sink)
 
 	// StatusCodeOverride is a mechanism for offers to behave as error handlers.
 	// Conditional request handling is disabled in this case.
 	if best.StatusCodeOverride != 0 {
 		rw.WriteHeader(best.StatusCodeOverride)
-		return best.Render(w, req, best.Data, chosen)
+		return render(best, w, req, chosen)
 	}
 
-	if best.Data == nil {
-		rw.WriteHeader(http.StatusNoContent)
-		return nil
+	if known.fromContent {
+		// the entity tag is obtained from the rendered representation
+		if err = render(best, w, req, chosen); err != nil {
+			return err
+		}
 	}
 
-	sendContent, err := dpkg.ConditionalRequest(rw, req, best.Data, chosen)
+	known.deriveETag(rw.Header(), buf)
+
+	sendContent, err := dpkg.ConditionalRequest(rw, req, known, chosen)
 	if err != nil {
 		return err
 	}
 
 	if !sendContent {
-		return nil // status will be 304
+		return nil // status will be 304 or 412
+	}
+
+	if known.fromContent {
+		return ctx.writeBuffered(rw, req, buf, ranges, rangeRequest)
+	}
+
+	if rangeRequest {
+		return renderRanges(best, rw, w, buf, req, chosen)
+	}
+
+	if ranges {
+		rw.Header().Set(headername.AcceptRanges, "bytes")
+	}
+
+	if req.Method == http.MethodHead {
+		return ctx.head(best, rw, w, counter, known, req, chosen)
 	}
 
 	if ctx.StatusCode > 0 {
 		rw.WriteHeader(ctx.StatusCode)
 	}
 
-	return best.Render(w, req, best.Data, chosen)
+	return render(best, w, req, chosen)
+}
+
+// head completes the headers for a HEAD request; there is no body. The content is not
+// obtained unless the processor may compress for itself (i.e. counter is not nil), in
+// which case it is rendered and discarded so that the headers are the same as for GET.
+func (ctx RespondWith) head(best *offerpkg.Match, rw http.ResponseWriter, w *offerpkg.ResponseWriter, counter *countingWriter, known *knownData, req *http.Request, chosen dpkg.Chosen) error {
+	if counter != nil {
+		if err := render(best, w, req, chosen); err != nil {
+			return err
+		}
+		rw.Header().Set(headername.ContentLength, strconv.FormatInt(counter.n, 10))
+	} else if known.meta.ContentLength > 0 && w.IsIdentity() {
+		rw.Header().Set(headername.ContentLength, strconv.FormatInt(known.meta.ContentLength, 10))
+	}
+
+	if ctx.StatusCode > 0 {
+		rw.WriteHeader(ctx.StatusCode)
+	}
+	return nil
+}
+
+// mayCompressItself is true when the match's processor may apply a content coding for itself,
+// which requires that the client accepts a content coding other than identity.
+func mayCompressItself(best *offerpkg.Match, req *http.Request) bool {
+	if best.IdentityProcessor || best.ContentEncoding != "" {
+		return false // the processor doesn't compress, or the response is already compressed
+	}
+	coding, _ := offerpkg.NegotiateContentCoding(req, true)
+	return coding != ""
+}
+
+// countingWriter discards everything written to it, counting the bytes.
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	c.n += int64(len(b))
+	return len(b), nil
+}
+
+// render renders the match to w, which is then closed so that any transcoder or
+// compressor is flushed (see offer.Match.ApplyHeaders).
+func render(best *offerpkg.Match, w *offerpkg.ResponseWriter, req *http.Request, chosen dpkg.Chosen) error {
+	err := best.Render(w, req, best.Data, chosen)
+	if e := w.Close(); err == nil {
+		err = e
+	}
+	return err
 }
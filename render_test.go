@@ -1,11 +1,16 @@
 package acceptable_test
 
 import (
+	"compress/gzip"
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/rickb777/acceptable"
+	"github.com/rickb777/acceptable/data"
 	"github.com/rickb777/acceptable/header"
 	. "github.com/rickb777/acceptable/headername"
 	"github.com/rickb777/acceptable/offer"
@@ -51,6 +56,25 @@ func Test_should_use_default_processor_if_no_accept_header(t *testing.T) {
 	expect.String(w.Body.String()).ToBe(t, "")
 }
 
+func Test_no_content_should_not_have_a_content_encoding(t *testing.T) {
+	// Given ...
+	a := offer.Text("test").With(nil, "en")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(AcceptEncoding, "gzip")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 204)
+	expect.String(w.Header().Get(ContentType)).ToBe(t, "text/test;charset=utf-8")
+	expect.String(w.Header().Get(ContentEncoding)).ToBe(t, "")
+	expect.String(w.Body.String()).ToBe(t, "")
+}
+
 func Test_should_use_catch_all_if_no_matching_accept_header(t *testing.T) {
 	// Given ...
 	a := offer.Text("csv").With("foo", "*")
@@ -298,6 +322,251 @@ func Test_should_match_utf8_charset_when_acceptable(t *testing.T) {
 		expect.Map(w.Header()).ToHaveLength(t, 3)
 		expect.String(w.Header().Get(ContentType)).ToBe(t, "text/html;charset=utf-8")
 		expect.String(w.Header().Get(ContentLanguage)).ToBe(t, "en")
-		expect.String(w.Header().Get(Vary)).ToBe(t, "Accept, Accept-Language")
+		expect.String(w.Header().Get(Vary)).ToBe(t, "Accept, Accept-Language, Accept-Charset")
+	}
+}
+
+func Test_should_compress_using_negotiated_content_coding(t *testing.T) {
+	// Given ...
+	a := offer.TextPlain().With("Hello world", "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(AcceptEncoding, "deflate;q=0.5, gzip")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Header().Get(ContentEncoding)).ToBe(t, "gzip")
+	expect.String(w.Header().Get(Vary)).ToBe(t, "Accept-Encoding")
+
+	zr, err := gzip.NewReader(w.Body)
+	expect.Error(err).Not().ToHaveOccurred(t)
+	b, _ := io.ReadAll(zr)
+	expect.String(string(b)).ToBe(t, "Hello world\n")
+}
+
+func Test_should_compress_and_transcode_together(t *testing.T) {
+	// Given ...
+	a := offer.TextPlain().With("café", "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(AcceptCharset, "iso-8859-1")
+	req.Header.Add(AcceptEncoding, "gzip")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(w.Header().Get(ContentType)).ToBe(t, "text/plain;charset=windows-1252")
+	expect.String(w.Header().Get(ContentEncoding)).ToBe(t, "gzip")
+
+	zr, err := gzip.NewReader(w.Body)
+	expect.Error(err).Not().ToHaveOccurred(t)
+	b, _ := io.ReadAll(zr)
+	expect.Slice(b).ToBe(t, 'c', 'a', 'f', 0xe9, '\n')
+}
+
+func Test_should_return_406_when_identity_is_refused_and_offer_cannot_compress(t *testing.T) {
+	// Given ...
+	a := offer.TextPlain().With("foo", "*").WithCompressionLevel(offer.NoCompression)
+	b := offer.JSON().With("bar", "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Accept, "text/plain, application/json;q=0.5")
+	req.Header.Add(AcceptEncoding, "identity;q=0, gzip")
+
+	// When ...
+	ranked := acceptable.RankedRequestMatches(req, a, b)
+
+	// Then ...
+	expect.Slice(ranked).ToHaveLength(t, 1)
+	expect.String(ranked[0].MediaType).ToBe(t, "application/json")
+	expect.String(ranked[0].ContentEncoding).ToBe(t, "gzip")
+
+	// When ...
+	w := httptest.NewRecorder()
+	err := acceptable.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 406)
+}
+
+func Test_should_not_exclude_an_offer_whose_processor_compresses_for_itself(t *testing.T) {
+	// Given ...
+	a := offer.Of(offer.JSONProcessor(offer.MidCompression), "application/json").With("foo", "*")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(AcceptEncoding, "gzip, identity;q=0")
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Header().Get(ContentEncoding)).ToBe(t, "gzip")
+
+	zr, err := gzip.NewReader(w.Body)
+	expect.Error(err).Not().ToHaveOccurred(t)
+	b, _ := io.ReadAll(zr)
+	expect.String(string(b)).ToBe(t, "\"foo\"\n")
+}
+
+func Test_should_choose_charset_supported_by_offer(t *testing.T) {
+	// Given ...
+	a := offer.TextPlain().With("foo", "*").WithCharsets("utf-8")
+	b := offer.XML("xml").With("bar", "*").WithCharsets("iso-8859-1", "utf-8")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(Accept, "text/plain, application/xml;q=0.5")
+	req.Header.Add(AcceptCharset, "iso-8859-1, utf-8;q=0.1")
+	w := httptest.NewRecorder()
+
+	// When ...
+	best := acceptable.BestRequestMatch(req, a, b)
+
+	// Then ...
+	expect.String(best.MediaType).ToBe(t, "text/plain")
+	expect.String(best.Charset).ToBe(t, "utf-8")
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, b)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(w.Header().Get(ContentType)).ToBe(t, "application/xml")
+	expect.String(w.Header().Get(Vary)).ToBe(t, "Accept, Accept-Charset")
+	expect.String(w.Body.String()).ToBe(t, "<?xml version=\"1.0\" encoding=\"windows-1252\"?>\n<string>bar</string>\n")
+}
+
+func Test_should_return_406_when_no_charset_is_acceptable(t *testing.T) {
+	// Given ...
+	a := offer.TextPlain().With("foo", "*").WithCharsets("utf-8")
+	b := offer.TextPlain().With("error", "*").WithCharsets("utf-8").CanHandle406As(http.StatusNotAcceptable)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(AcceptCharset, "iso-8859-1")
+	w1 := httptest.NewRecorder()
+	w2 := httptest.NewRecorder()
+
+	// When ...
+	err1 := acceptable.RenderBestMatch(w1, req, a)
+	err2 := acceptable.RenderBestMatch(w2, req, a, b)
+
+	// Then ...
+	expect.Error(err1).Not().ToHaveOccurred(t)
+	expect.Number(w1.Code).ToBe(t, 406)
+
+	expect.Error(err2).Not().ToHaveOccurred(t)
+	expect.Number(w2.Code).ToBe(t, 406)
+	expect.String(w2.Body.String()).ToBe(t, "error\n")
+}
+
+func Test_should_send_result_of_put_without_evaluating_preconditions(t *testing.T) {
+	// Given ...
+	// the handler has already made the change, so its new entity tag differs from If-Match
+	a := offer.TextPlain().With(data.Of("updated").ETag("v2"), "*")
+
+	req, _ := http.NewRequest("PUT", "/", nil)
+	req.Header.Add(IfMatch, `"v1"`)
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, a)
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.Number(w.Code).ToBe(t, 200)
+	expect.String(w.Body.String()).ToBe(t, "updated\n")
+}
+
+func Test_supplier_should_be_given_the_request(t *testing.T) {
+	// Given ...
+	type key struct{}
+	d := data.Lazy(func(chosen data.Chosen) (any, error) {
+		return chosen.Request.PathValue("id") + " " + chosen.Context().Value(key{}).(string), nil
+	})
+
+	req, _ := http.NewRequest("GET", "/p/42", nil)
+	req.SetPathValue("id", "42")
+	req = req.WithContext(context.WithValue(req.Context(), key{}, "user1"))
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, offer.TextPlain().With(d, "*"))
+
+	// Then ...
+	expect.Error(err).Not().ToHaveOccurred(t)
+	expect.String(w.Body.String()).ToBe(t, "42 user1\n")
+}
+
+func Test_sequence_should_stop_when_the_client_goes_away(t *testing.T) {
+	// Given ...
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	count := 0
+	d := data.Sequence(func(data.Chosen) (any, error) {
+		count++
+		if count == 3 {
+			cancel()
+		}
+		return count, nil // never-ending
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	// When ...
+	err := acceptable.RenderBestMatch(w, req, offer.JSON().With(d, "*"))
+
+	// Then ...
+	expect.Error(err).ToWrap(t, context.Canceled)
+	expect.Number(count).ToBe(t, 3)
+}
+
+func Test_shared_offers_should_be_safe_for_concurrent_requests(t *testing.T) {
+	// Given ...
+	d := data.Sequence(func(chosen data.Chosen) (any, error) {
+		// each request counts independently, using its own context
+		n := chosen.Context().Value(counterKey{}).(*int)
+		*n++
+		if *n > 3 {
+			return nil, nil
+		}
+		return *n, nil
+	})
+	available := []offer.Offer{offer.JSON().With(d, "*")}
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 20)
+
+	// When ...
+	for i := range bodies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "/", nil)
+			req = req.WithContext(context.WithValue(req.Context(), counterKey{}, new(int)))
+			w := httptest.NewRecorder()
+			_ = acceptable.RenderBestMatch(w, req, available...)
+			bodies[i] = w.Body.String()
+		}()
+	}
+	wg.Wait()
+
+	// Then ...
+	for i, body := range bodies {
+		expect.String(body).I(i).ToBe(t, "[1\n,2\n,3\n]\n")
 	}
 }
+
+type counterKey struct{}
@@ -9,10 +9,27 @@ import (
 
 // TextHtmlOffer is an Offer for text/html content using the Template() processor.
 func TextHtmlOffer(dir, suffix string, funcMap template.FuncMap) offer.Offer {
-	return offer.Of(Templates(dir, suffix, funcMap), contenttype.TextHTML)
+	return DefaultConfig().TextHtmlOffer(dir, suffix, funcMap)
 }
 
 // ApplicationXhtmlOffer is an Offer for application/xhtml+xml content using the Template() processor.
 func ApplicationXhtmlOffer(dir, suffix string, funcMap template.FuncMap) offer.Offer {
-	return offer.Of(Templates(dir, suffix, funcMap), contenttype.ApplicationXHTML)
+	return DefaultConfig().ApplicationXhtmlOffer(dir, suffix, funcMap)
+}
+
+// TextHtmlOffer is an Offer for text/html content using this configuration.
+func (c Config) TextHtmlOffer(dir, suffix string, funcMap template.FuncMap) offer.Offer {
+	return c.offer(c.doTemplates(dir, suffix, funcMap), contenttype.TextHTML)
+}
+
+// ApplicationXhtmlOffer is an Offer for application/xhtml+xml content using this configuration.
+func (c Config) ApplicationXhtmlOffer(dir, suffix string, funcMap template.FuncMap) offer.Offer {
+	return c.offer(c.doTemplates(dir, suffix, funcMap), contenttype.ApplicationXHTML)
+}
+
+// offer constructs an Offer for a template processor, which does not compress for itself.
+func (c Config) offer(processor offer.Processor, contentType string) offer.Offer {
+	o := offer.Of(processor, contentType).WithCompressionLevel(c.GZIPLevel)
+	o.IdentityProcessor = true
+	return o
 }
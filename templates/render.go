@@ -9,23 +9,27 @@ import (
 	dpkg "github.com/rickb777/acceptable/data"
 	"github.com/rickb777/acceptable/internal"
 	"github.com/rickb777/acceptable/offer"
+	"github.com/spf13/afero"
 )
 
 // DefaultPage is the template name when a blank string is supplied.
 // Alter this during startup if required.
 var DefaultPage = "_index.html"
 
-func productionProcessor(root *tmplpkg.Template) offer.Processor {
+func productionProcessor(defaultPage string, root *tmplpkg.Template) offer.Processor {
 	return func(w io.Writer, req *http.Request, data dpkg.Data, chosen dpkg.Chosen) (err error) {
 		p := internal.EnsureNewline(w)
 
-		d, _, err := data.Content(chosen)
+		content := data.Content(chosen)
+		defer content.Close()
+
+		d, _, err := content.Next()
 		if err != nil {
 			return err
 		}
 
 		if chosen.Template == "" {
-			chosen.Template = DefaultPage
+			chosen.Template = defaultPage
 		}
 		return root.ExecuteTemplate(p, chosen.Template, d)
 	}
@@ -33,42 +37,45 @@ func productionProcessor(root *tmplpkg.Template) offer.Processor {
 
 //-------------------------------------------------------------------------------------------------
 
-func debugProcessor(root *tmplpkg.Template, rootDir, suffix string, files map[string]time.Time, funcMap tmplpkg.FuncMap) offer.Processor {
+func debugProcessor(c Config, root *tmplpkg.Template, rootDir, suffix string, files map[string]time.Time, funcMap tmplpkg.FuncMap) offer.Processor {
 	return func(w io.Writer, req *http.Request, data dpkg.Data, chosen dpkg.Chosen) (err error) {
 		if chosen.Template == "" {
-			chosen.Template = DefaultPage
+			chosen.Template = c.DefaultPage
 		}
 
 		path := rootDir + "/" + chosen.Template
 		if _, exists := files[path]; !exists {
-			files = findTemplates(rootDir, suffix)
+			files = findTemplates(c.Fs, rootDir, suffix)
 		}
 
-		d, _, err := data.Content(chosen)
+		content := data.Content(chosen)
+		defer content.Close()
+
+		d, _, err := content.Next()
 		if err != nil {
 			return err
 		}
 
 		p := internal.EnsureNewline(w)
-		root = getCurrentTemplateTree(root, rootDir, suffix, files, funcMap)
+		root = getCurrentTemplateTree(c.Fs, root, rootDir, suffix, files, funcMap)
 
 		return root.ExecuteTemplate(p, chosen.Template, d)
 	}
 }
 
-func getCurrentTemplateTree(root *tmplpkg.Template, rootDir, suffix string, files map[string]time.Time, funcMap tmplpkg.FuncMap) *tmplpkg.Template {
-	changed := checkForChanges(files)
+func getCurrentTemplateTree(fs afero.Fs, root *tmplpkg.Template, rootDir, suffix string, files map[string]time.Time, funcMap tmplpkg.FuncMap) *tmplpkg.Template {
+	changed := checkForChanges(fs, files)
 	if changed {
-		root = parseTemplates(rootDir, files, funcMap)
+		root = parseTemplates(fs, rootDir, files, funcMap)
 	}
 	return root
 }
 
-func checkForChanges(files map[string]time.Time) bool {
+func checkForChanges(fs afero.Fs, files map[string]time.Time) bool {
 	changed := false
 
 	for path, modTime := range files {
-		fi, err := Fs.Stat(path)
+		fi, err := fs.Stat(path)
 		if err == nil {
 			if fi.ModTime().After(modTime) {
 				files[path] = fi.ModTime()
@@ -13,6 +13,9 @@ import (
 )
 
 // Fs is used to obtain file information and content. It can be stubbed for testing.
+//
+// This and the other package-level settings are the defaults for DefaultConfig; use Config
+// for independent settings.
 var Fs = afero.NewOsFs()
 
 // ReloadOnTheFly enables a development mode that reloads template files whenever they
@@ -20,13 +23,43 @@ var Fs = afero.NewOsFs()
 // (false) for production.
 var ReloadOnTheFly = false
 
-// GZIPLevel sets the compression strength when gzip is applied to a response entity.
+// GZIPLevel sets the compression strength when a content coding such as gzip is applied to a response entity.
 // This is in the range 1 to 9 inclusive (see gzip.NewWriterLevel). High values should
 // be avoided because the cpu cost is high but the benefit may not be sufficient.
 //
 // This controls template responses only.
 var GZIPLevel = offer.MidCompression
 
+// Config holds the settings used for template processing. Unlike the package-level
+// settings Fs, ReloadOnTheFly, GZIPLevel and DefaultPage, each Config is independent,
+// so different parts of a program (or parallel tests) can use different settings.
+type Config struct {
+	// Fs is used to obtain file information and content. If nil, the package-level Fs is used.
+	Fs afero.Fs
+
+	// ReloadOnTheFly enables a development mode that reloads template files whenever they
+	// change (see the package-level ReloadOnTheFly).
+	ReloadOnTheFly bool
+
+	// GZIPLevel sets the compression strength when a content coding such as gzip is applied
+	// to a response entity (see the package-level GZIPLevel).
+	GZIPLevel int
+
+	// DefaultPage is the template name when a blank string is supplied. If blank, the
+	// package-level DefaultPage is used.
+	DefaultPage string
+}
+
+// DefaultConfig returns a Config holding the current package-level settings.
+func DefaultConfig() Config {
+	return Config{
+		Fs:             Fs,
+		ReloadOnTheFly: ReloadOnTheFly,
+		GZIPLevel:      GZIPLevel,
+		DefaultPage:    DefaultPage,
+	}
+}
+
 // Templates finds all the templates in the directory dir and its subdirectories
 // that have names ending with the given suffix (usually ".html").
 //
@@ -36,41 +69,52 @@ var GZIPLevel = offer.MidCompression
 //
 // A processor is returned that handles requests using the templates available.
 //
-// The response will use gzip compression (see [GZIPLevel]) when the client requests it.
+// The response will be compressed (see [GZIPLevel]) when the client accepts a content coding such as gzip.
 func Templates(dir, suffix string, funcMap template.FuncMap) offer.Processor {
-	return offer.GZIPProcessor(GZIPLevel, doTemplates(dir, suffix, funcMap))
+	return DefaultConfig().Templates(dir, suffix, funcMap)
+}
+
+// Templates is as per the Templates function, using this configuration.
+func (c Config) Templates(dir, suffix string, funcMap template.FuncMap) offer.Processor {
+	return offer.EncodingProcessor(c.GZIPLevel, c.doTemplates(dir, suffix, funcMap))
 }
 
-func doTemplates(dir, suffix string, funcMap template.FuncMap) offer.Processor {
+func (c Config) doTemplates(dir, suffix string, funcMap template.FuncMap) offer.Processor {
+	if c.Fs == nil {
+		c.Fs = Fs
+	}
+	if c.DefaultPage == "" {
+		c.DefaultPage = DefaultPage
+	}
 	if funcMap == nil {
 		funcMap = template.FuncMap{}
 	}
 
 	rootDir := filepath.Clean(dir)
 
-	files := findTemplates(rootDir, suffix)
+	files := findTemplates(c.Fs, rootDir, suffix)
 
 	if len(files) == 0 {
 		panic("No HTML files were found in " + rootDir)
 	}
 
-	root := parseTemplates(rootDir, files, funcMap)
+	root := parseTemplates(c.Fs, rootDir, files, funcMap)
 
-	if ReloadOnTheFly {
-		return debugProcessor(root, rootDir, suffix, files, funcMap)
+	if c.ReloadOnTheFly {
+		return debugProcessor(c, root, rootDir, suffix, files, funcMap)
 	}
 
-	return productionProcessor(root)
+	return productionProcessor(c.DefaultPage, root)
 }
 
 //-------------------------------------------------------------------------------------------------
 
-func findTemplates(rootDir, suffix string) map[string]time.Time {
+func findTemplates(fs afero.Fs, rootDir, suffix string) map[string]time.Time {
 	cleanRoot := filepath.Clean(rootDir)
 	files := make(map[string]time.Time)
 
 	for _, sfx := range strings.Split(suffix, suffixSeparator) {
-		err := afero.Walk(Fs, cleanRoot, func(path string, info os.FileInfo, e1 error) error {
+		err := afero.Walk(fs, cleanRoot, func(path string, info os.FileInfo, e1 error) error {
 			if e1 != nil {
 				panic(fmt.Sprintf("Cannot load templates from: %s: %v\n", rootDir, e1))
 			}
@@ -90,12 +134,12 @@ func findTemplates(rootDir, suffix string) map[string]time.Time {
 	return files
 }
 
-func parseTemplates(rootDir string, files map[string]time.Time, funcMap template.FuncMap) *template.Template {
+func parseTemplates(fs afero.Fs, rootDir string, files map[string]time.Time, funcMap template.FuncMap) *template.Template {
 	pfx := len(rootDir) + 1
 	root := template.New("")
 
 	for path := range files {
-		b, e2 := afero.ReadFile(Fs, path)
+		b, e2 := afero.ReadFile(fs, path)
 		if e2 != nil {
 			panic(fmt.Sprintf("Read template error: %s: %v\n", path, e2))
 		}
@@ -145,6 +145,25 @@ func TestDebugInstance_using_fakes(t *testing.T) {
 	expect.Slice(rec.opened).ToBeEmpty(t)
 }
 
+func TestConfigInstance_using_its_own_fs(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "synthetic/index.html", []byte("<html>{{.Title}}-Index</html>"), 0644)
+
+	cfg := templates.Config{Fs: fs, DefaultPage: "index.html"}
+
+	render := cfg.Templates("synthetic", ".html", nil)
+
+	data := dpkg.Of(map[string]string{"Title": "Hello"})
+
+	req := &http.Request{}
+	w := httptest.NewRecorder()
+
+	err := render(w, req, data, dpkg.Chosen{Language: "en"})
+	expect.Error(err).Not().ToHaveOccurred(t)
+
+	expect.String(w.Body.String()).ToBe(t, "<html>Hello-Index</html>")
+}
+
 //-------------------------------------------------------------------------------------------------
 
 type recorder struct {
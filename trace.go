@@ -0,0 +1,176 @@
+package acceptable
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// TraceKind identifies the kind of decision recorded in a TraceStep.
+type TraceKind string
+
+const (
+	// TraceExcluded means an offer was removed because a media range excluded it (q=0).
+	TraceExcluded TraceKind = "excluded"
+
+	// TraceNoMatch means an offer did not match any acceptable media range in a pass.
+	TraceNoMatch TraceKind = "no-match"
+
+	// TraceNoLanguageMatch means an offer matched a media range but none of its languages
+	// were acceptable.
+	TraceNoLanguageMatch TraceKind = "no-language-match"
+
+	// TraceMatched means an offer and language matched in a pass.
+	TraceMatched TraceKind = "matched"
+
+	// TraceLanguageFallback means no language was acceptable, so the content type alone
+	// was matched and the offer's first language used instead (RFC-7231 section 5.3.5).
+	TraceLanguageFallback TraceKind = "language-fallback"
+
+	// TraceCharset means the response character set was chosen.
+	TraceCharset TraceKind = "charset"
+
+	// TraceContentCoding means the response content coding was chosen. A blank
+	// ContentCoding means the identity coding.
+	TraceContentCoding TraceKind = "content-coding"
+
+	// TraceFallback406 means no offer was acceptable, so an offer that handles the
+	// 406-Not Acceptable case was chosen.
+	TraceFallback406 TraceKind = "fallback-406"
+
+	// TraceNotAcceptable means no offer was acceptable.
+	TraceNotAcceptable TraceKind = "not-acceptable"
+)
+
+// TraceStep is one decision made by the negotiation algorithm. Only the fields relevant to
+// its Kind are set.
+type TraceStep struct {
+	Kind TraceKind
+	// Pass is "exact" or "near", for steps made while matching.
+	Pass string
+	// Offer describes the offer concerned, if any.
+	Offer string
+	// MediaRange is the accepted media range concerned, if any.
+	MediaRange string
+	// Language is the chosen language, if any.
+	Language string
+	// Charset is the chosen character set, if any.
+	Charset string
+	// ContentCoding is the chosen content coding, if any.
+	ContentCoding string
+	// Quality is the effective quality of a match.
+	Quality float64
+	// Reason provides further explanation, if any.
+	Reason string
+}
+
+// Trace records the decisions made by the negotiation algorithm for a single request.
+// Attach one to the request context using WithTrace, then it will be populated by
+// BestRequestMatch, RankedRequestMatches and the rendering functions that use them.
+//
+// A Trace implements slog.LogValuer so that it can be logged directly.
+type Trace struct {
+	// Request is the method and URL of the request.
+	Request string
+	Steps   []TraceStep
+}
+
+type traceKey struct{}
+
+// WithTrace returns a copy of ctx that carries a trace, which will be populated
+// when content negotiation happens for a request using this context.
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// TraceFrom gets the trace carried by ctx, or nil if there is none.
+func TraceFrom(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// Kinds lists the kinds of all the steps, in order. This is convenient in tests.
+func (t *Trace) Kinds() []TraceKind {
+	if t == nil {
+		return nil
+	}
+	kinds := make([]TraceKind, len(t.Steps))
+	for i, s := range t.Steps {
+		kinds[i] = s.Kind
+	}
+	return kinds
+}
+
+func (t *Trace) add(step TraceStep) {
+	if t != nil {
+		t.Steps = append(t.Steps, step)
+	}
+}
+
+// LogValue implements slog.LogValuer.
+func (t *Trace) LogValue() slog.Value {
+	if t == nil {
+		return slog.GroupValue()
+	}
+	attrs := make([]slog.Attr, 0, len(t.Steps)+1)
+	attrs = append(attrs, slog.String("request", t.Request))
+	for i, s := range t.Steps {
+		attrs = append(attrs, slog.Any(strconv.Itoa(i), s))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// LogValue implements slog.LogValuer.
+func (s TraceStep) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("kind", string(s.Kind))}
+	attrs = appendIfSet(attrs, "pass", s.Pass)
+	attrs = appendIfSet(attrs, "offer", s.Offer)
+	attrs = appendIfSet(attrs, "mediaRange", s.MediaRange)
+	attrs = appendIfSet(attrs, "language", s.Language)
+	attrs = appendIfSet(attrs, "charset", s.Charset)
+	attrs = appendIfSet(attrs, "contentCoding", s.ContentCoding)
+	if s.Kind == TraceMatched {
+		attrs = append(attrs, slog.Float64("q", s.Quality))
+	}
+	attrs = appendIfSet(attrs, "reason", s.Reason)
+	return slog.GroupValue(attrs...)
+}
+
+func appendIfSet(attrs []slog.Attr, key, value string) []slog.Attr {
+	if value != "" {
+		attrs = append(attrs, slog.String(key, value))
+	}
+	return attrs
+}
+
+func (s TraceStep) String() string {
+	b := &strings.Builder{}
+	b.WriteString(string(s.Kind))
+	if s.Pass != "" {
+		fmt.Fprintf(b, " (%s)", s.Pass)
+	}
+	if s.Offer != "" {
+		fmt.Fprintf(b, " offer %s", s.Offer)
+	}
+	if s.MediaRange != "" {
+		fmt.Fprintf(b, " for %s", s.MediaRange)
+	}
+	if s.Language != "" {
+		fmt.Fprintf(b, " lang=%s", s.Language)
+	}
+	if s.Charset != "" {
+		fmt.Fprintf(b, " charset=%s", s.Charset)
+	}
+	if s.ContentCoding != "" {
+		fmt.Fprintf(b, " coding=%s", s.ContentCoding)
+	}
+	if s.Kind == TraceMatched {
+		fmt.Fprintf(b, " q=%g", s.Quality)
+	}
+	if s.Reason != "" {
+		fmt.Fprintf(b, ": %s", s.Reason)
+	}
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package acceptable_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rickb777/acceptable"
+	. "github.com/rickb777/acceptable/headername"
+	"github.com/rickb777/acceptable/offer"
+	"github.com/rickb777/expect"
+)
+
+func Test_trace_should_record_exclusion_match_and_charset(t *testing.T) {
+	// Given ...
+	a := offer.Of(nil, "text/csv").With("csv", "*")
+	b := offer.Of(nil, "application/json").With("json", "*")
+
+	trace := &acceptable.Trace{}
+	req, _ := http.NewRequest("GET", "/foo", nil)
+	req = req.WithContext(acceptable.WithTrace(req.Context(), trace))
+	req.Header.Add(Accept, "text/csv;q=0, application/*")
+	req.Header.Add(AcceptCharset, "iso-8859-1")
+
+	// When ...
+	best := acceptable.BestRequestMatch(req, a, b)
+
+	// Then ...
+	expect.String(best.MediaType).ToBe(t, "application/json")
+	expect.String(trace.Request).ToBe(t, "GET /foo")
+	expect.Slice(trace.Kinds()).ToBe(t,
+		acceptable.TraceExcluded,
+		acceptable.TraceNoMatch,
+		acceptable.TraceMatched,
+		acceptable.TraceCharset,
+	)
+	expect.String(trace.Steps[0].Offer).ToContain(t, "text/csv")
+	expect.String(trace.Steps[1].Pass).ToBe(t, "exact")
+	expect.String(trace.Steps[2].Pass).ToBe(t, "near")
+	expect.String(trace.Steps[3].Charset).ToBe(t, "windows-1252")
+}
+
+func Test_trace_should_record_language_fallback(t *testing.T) {
+	// Given ...
+	a := offer.Of(nil, "text/html").With("en", "en")
+
+	trace := &acceptable.Trace{}
+	req, _ := http.NewRequest("GET", "/", nil)
+	req = req.WithContext(acceptable.WithTrace(req.Context(), trace))
+	req.Header.Add(Accept, "text/html")
+	req.Header.Add(AcceptLanguage, "fr")
+
+	// When ...
+	acceptable.BestRequestMatch(req, a)
+
+	// Then ...
+	expect.Slice(trace.Kinds()).ToBe(t,
+		acceptable.TraceNoLanguageMatch,
+		acceptable.TraceNoLanguageMatch,
+		acceptable.TraceLanguageFallback,
+		acceptable.TraceMatched, // exact
+		acceptable.TraceMatched, // near
+		acceptable.TraceCharset,
+	)
+}
+
+func Test_trace_should_record_406_fallback(t *testing.T) {
+	// Given ...
+	a := offer.Of(nil, "text/html").With("en", "en").CanHandle406As(http.StatusNotAcceptable)
+
+	trace := &acceptable.Trace{}
+	req, _ := http.NewRequest("GET", "/", nil)
+	req = req.WithContext(acceptable.WithTrace(req.Context(), trace))
+	req.Header.Add(Accept, "image/png")
+
+	// When ...
+	acceptable.BestRequestMatch(req, a)
+
+	// Then ...
+	expect.Slice(trace.Kinds()).ToBe(t,
+		acceptable.TraceNoMatch,
+		acceptable.TraceNoMatch,
+		acceptable.TraceNotAcceptable,
+		acceptable.TraceFallback406,
+	)
+}
+
+func Test_trace_should_be_logged_with_slog(t *testing.T) {
+	// Given ...
+	a := offer.Of(nil, "text/html").With("en", "en")
+
+	trace := &acceptable.Trace{}
+	req, _ := http.NewRequest("GET", "/", nil)
+	req = req.WithContext(acceptable.WithTrace(req.Context(), trace))
+	req.Header.Add(Accept, "text/html")
+	acceptable.BestRequestMatch(req, a)
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+
+	// When ...
+	logger.Info("negotiated", "trace", trace)
+
+	// Then ...
+	s := buf.String()
+	expect.Bool(strings.Contains(s, "trace.request=\"GET /\"")).I(s).ToBeTrue(t)
+	expect.Bool(strings.Contains(s, "trace.0.kind=matched trace.0.pass=exact")).I(s).ToBeTrue(t)
+	expect.Bool(strings.Contains(s, "trace.2.kind=charset")).I(s).ToBeTrue(t)
+}
+
+func Test_without_trace_is_nil(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	expect.Value(acceptable.TraceFrom(req.Context())).ToBeNil(t)
+}